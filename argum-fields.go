@@ -1,13 +1,36 @@
 package argum
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	valueInterface      = reflect.TypeOf((*Value)(nil)).Elem()
+	typerInterface      = reflect.TypeOf((*typer)(nil)).Elem()
+	optionalInterface   = reflect.TypeOf((*optional)(nil)).Elem()
+)
+
+// Value is the interface to the dynamic value stored in a field, it is compatible with flag.Value.
+// Set is called with raw value for each occurrence of argument, optional method Type() string
+// returns name of value shown in usage and help
+type Value interface {
+	String() string
+	Set(string) error
+}
+
+type typer interface {
+	Type() string
+}
+
 type field struct {
 	v     reflect.Value
 	field reflect.StructField
@@ -22,26 +45,72 @@ type field struct {
 	cmd          bool
 	oneof        bool
 	emb          bool
+	negatable    bool
+	count        bool
+	appendDef    bool
+	rest         bool
 	variants     []string
 
-	help string
-	def  string
-
-	taken bool
-	s     *structure
+	help        string
+	def         string
+	sep         string
+	duplicate   string
+	placeholder string
+	layout      string
+	schemes     []string
+	path        []string
+	exts        []string
+	xor         string
+	xorreq      bool
+
+	requires       []string
+	conflicts      []string
+	requiredIf     []string
+	requiredUnless []string
+
+	min, max reflect.Value
+	minlen   int
+	maxlen   int
+	pattern  *regexp.Regexp
+
+	fileDef bool
+	given   string
+	taken   bool
+	s       *structure
 }
 
 func (s *structure) newField(sf reflect.StructField, v reflect.Value) (f *field, err error) {
 	f = &field{
-		v:     v,
-		field: sf,
-		name:  strings.ToLower(sf.Name),
-		help:  sf.Tag.Get("help"),
-		def:   sf.Tag.Get("default"),
+		v:           v,
+		field:       sf,
+		name:        strings.ToLower(sf.Name),
+		help:        sf.Tag.Get("help"),
+		def:         sf.Tag.Get("default"),
+		sep:         ",",
+		duplicate:   sf.Tag.Get("duplicate"),
+		placeholder: sf.Tag.Get("placeholder"),
+		layout:      sf.Tag.Get("layout"),
+	}
+
+	if sep, ok := sf.Tag.Lookup("sep"); ok {
+		f.sep = sep
+	}
+
+	if scheme := sf.Tag.Get("scheme"); scheme != "" {
+		f.schemes = strings.Split(scheme, "|")
+	}
+
+	if f.xor = sf.Tag.Get("xor"); f.xor == "" {
+		f.xor = sf.Tag.Get("xorreq")
+		f.xorreq = f.xor != ""
+	}
+
+	if err = f.prepareChecks(sf); err != nil {
+		return
 	}
 
 	// prepare commands
-	if f.v.Kind() == reflect.Ptr || f.v.Kind() == reflect.Struct {
+	if (f.v.Kind() == reflect.Ptr || f.v.Kind() == reflect.Struct) && !isValueType(f.v.Type()) {
 		f.cmd = true
 
 		var ptr interface{}
@@ -53,24 +122,18 @@ func (s *structure) newField(sf reflect.StructField, v reflect.Value) (f *field,
 			ptr = reflect.New(v.Type()).Interface()
 		}
 
-		f.s, err = prepareStructure(ptr)
+		f.s, err = prepareFields(ptr)
 		if err != nil {
 			return
 		}
 	}
 
-	// set default values
+	// set default values, files are opened only if argument is not given
 	if !f.cmd && f.def != "" && v.CanSet() {
-
-		val := fmt.Sprintf("%v", v.Interface())
-		zero := fmt.Sprintf("%v", reflect.Zero(v.Type()).Interface())
-
-		if val == zero {
-			x, err := f.transformValue(strings.Split(f.def, ","))
-			if err != nil {
-				return f, err
-			}
-			f.v.Set(x)
+		if isFileType(f.v.Type()) {
+			f.fileDef = true
+		} else if err = f.setDefault(); err != nil {
+			return
 		}
 	}
 
@@ -98,6 +161,15 @@ func (s *structure) newField(sf reflect.StructField, v reflect.Value) (f *field,
 		case key == "emb" || key == "embedded":
 			f.emb = true
 			f.s.emb = true
+		case key == "negatable":
+			f.negatable = true
+		case key == "count":
+			f.count = true
+		case key == "append":
+			f.appendDef = true
+		case key == "rest":
+			f.rest = true
+			f.pos = true
 		default:
 			err = fmt.Errorf("argument '%s' have unexpected tag description: %s", f.name, key)
 		}
@@ -114,7 +186,29 @@ func (s *structure) newField(sf reflect.StructField, v reflect.Value) (f *field,
 		}
 	}
 
-	if f.short != "" && f.v.Kind() == reflect.Bool && !f.req {
+	switch f.duplicate {
+	case "", "error", "first", "last":
+	default:
+		err = fmt.Errorf("argument '%s' have unexpected duplicate policy: %s", f.name, f.duplicate)
+		return
+	}
+
+	if f.negatable && (!f.isBool() || f.long == "") {
+		err = fmt.Errorf("invalid `%s`, only boolean argument with long key can be negatable", f.name)
+		return
+	}
+
+	if f.rest && f.v.Type() != reflect.TypeOf([]string{}) {
+		err = fmt.Errorf("invalid `%s`, only []string argument can be rest", f.name)
+		return
+	}
+
+	if f.count && !isInt(f.v.Kind()) {
+		err = fmt.Errorf("invalid `%s`, only integer argument can be counter", f.name)
+		return
+	}
+
+	if f.short != "" && (f.isBool() || f.count) && !f.req {
 		f.shortboolean = true
 	}
 
@@ -138,6 +232,87 @@ func (f *field) autoShortLong(fieldname string) {
 	}
 }
 
+// setDefault set default value to field if it has zero value
+func (f *field) setDefault() error {
+	val := fmt.Sprintf("%v", f.v.Interface())
+	zero := fmt.Sprintf("%v", reflect.Zero(f.v.Type()).Interface())
+	if val != zero {
+		return nil
+	}
+
+	if v := f.value(); v != nil {
+		return v.Set(f.def)
+	}
+
+	x, err := f.transformValue(splitValuesSep(f.def, f.separator()))
+	if err != nil {
+		return err
+	}
+	f.v.Set(x)
+
+	// default value of Optional is not considered as set
+	if o, ok := f.v.Addr().Interface().(optional); ok {
+		o.unset()
+	}
+
+	return nil
+}
+
+// setRest set arguments to rest field as is
+func (f *field) setRest(args []string) {
+	f.v.Set(reflect.ValueOf(append([]string{}, args...)))
+	f.taken = true
+}
+
+// displayName return name of argument for error messages: long or short key, or name of positional argument
+func (f *field) displayName() string {
+	switch {
+	case f.long != "":
+		return f.long
+	case f.short != "":
+		return f.short
+	case f.pos:
+		return "<" + f.name + ">"
+	}
+	return f.name
+}
+
+// matchKey report whether argument is short or long key of field
+func (f *field) matchKey(arg string) bool {
+	return f.short != "" && f.short == arg || f.long != "" && equalName(f.long, arg) || f.negatable && equalName(f.negLong(), arg)
+}
+
+// variant return declared variant matching value
+func (f *field) variant(val string) (string, bool) {
+	for _, v := range f.variants {
+		if equalName(v, val) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// duplicatePolicy return policy for argument given several times
+func (f *field) duplicatePolicy() DuplicatePolicy {
+	switch f.duplicate {
+	case "error":
+		return DuplicateError
+	case "first":
+		return DuplicateFirst
+	case "last":
+		return DuplicateLast
+	}
+	return Duplicates
+}
+
+// negLong return negative form of long key, like --no-cache, if argument is negatable
+func (f *field) negLong() string {
+	if !f.negatable {
+		return ""
+	}
+	return "--no-" + f.long[2:]
+}
+
 func (f *field) nameMatch(arg string) bool {
 	if matchLong(arg) {
 		return f.long == arg
@@ -149,6 +324,14 @@ func (f *field) nameMatch(arg string) bool {
 }
 
 func (f *field) setBool(arg string, vals []string, next []string) (int, error) {
+	if f.negatable && arg == f.negLong() {
+		if len(vals) > 0 {
+			return 0, fmt.Errorf("argument '%s' does not take a value", arg)
+		}
+		_, err := f.setValue("false")
+		return 0, err
+	}
+
 	if len(next) > 0 {
 		_, err := strconv.ParseBool(next[0])
 		if err == nil {
@@ -176,10 +359,78 @@ func (f *field) setBool(arg string, vals []string, next []string) (int, error) {
 	return 0, fmt.Errorf("unexpected value %s", arg)
 }
 
+// isBool report whether field is boolean, pointer to boolean or Optional boolean
+func (f *field) isBool() bool {
+	if !f.v.IsValid() {
+		return false
+	}
+
+	t := f.v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if elem, ok := optionalElem(t); ok {
+		t = elem
+	}
+
+	return t.Kind() == reflect.Bool
+}
+
+// repeatable argument may be specified several times
+func (f *field) repeatable() bool {
+	return f.count || f.isValue() || f.v.Kind() == reflect.Map || f.isSlice()
+}
+
+// isSlice report whether field takes several values
+func (f *field) isSlice() bool {
+	return f.v.Kind() == reflect.Slice && !isValueType(f.v.Type())
+}
+
+// separator return string by which argument values are split, empty separator disables splitting
+func (f *field) separator() string {
+	if f.isValue() {
+		return ""
+	}
+	return f.sep
+}
+
+// isValue report whether field implements Value interface
+func (f *field) isValue() bool {
+	return f.v.IsValid() && isValueInterface(f.v.Type())
+}
+
+// value return field as Value interface, nil pointer is allocated, if field does not implement it, then returns nil
+func (f *field) value() Value {
+	if !f.isValue() {
+		return nil
+	}
+
+	if f.v.Kind() == reflect.Ptr && f.v.Type().Implements(valueInterface) {
+		if f.v.IsNil() {
+			f.v.Set(reflect.New(f.v.Type().Elem()))
+		}
+		return f.v.Interface().(Value)
+	}
+
+	return f.v.Addr().Interface().(Value)
+}
+
+// increment counter argument, each occurrence of it add one to value
+func (f *field) increment() {
+	f.taken = true
+	f.v.SetInt(f.v.Int() + 1)
+}
+
 func (f *field) setStruct(args []string) (int, error) {
 	n, err := f.s.parseArgs(args)
+	f.selectStruct()
+	return n, err
+}
 
+// selectStruct mark nested structure as selected and set its value to field
+func (f *field) selectStruct() {
 	f.taken = true
+	f.s.taken = true
 
 	if f.v.Kind() == reflect.Ptr {
 		f.v.Set(reflect.ValueOf(f.s.i))
@@ -192,8 +443,6 @@ func (f *field) setStruct(args []string) (int, error) {
 			f.taken = true
 		}
 	}
-
-	return n, err
 }
 
 func (f *field) setValue(vals ...string) (int, error) {
@@ -202,9 +451,19 @@ func (f *field) setValue(vals ...string) (int, error) {
 	}
 
 	if len(f.variants) > 0 {
-		if !contains(f.variants, vals[0]) {
+		v, ok := f.variant(vals[0])
+		if !ok {
 			return 0, fmt.Errorf("impossible value %s, choose from %s", vals[0], f.variants)
 		}
+		vals = append([]string{v}, vals[1:]...)
+	}
+
+	if v := f.value(); v != nil {
+		if err := v.Set(vals[0]); err != nil {
+			return 0, fmt.Errorf("invalid value %s for '%s': %s", vals[0], f.name, err)
+		}
+		f.taken = true
+		return 1, nil
 	}
 
 	if len(vals) == 1 && vals[0] == "" {
@@ -216,130 +475,309 @@ func (f *field) setValue(vals ...string) (int, error) {
 		return 0, err
 	}
 
+	switch {
+	case isValueType(rv.Type()):
+	case rv.Kind() == reflect.Map:
+		if f.taken {
+			for _, k := range rv.MapKeys() {
+				f.v.SetMapIndex(k, rv.MapIndex(k))
+			}
+		} else {
+			f.v.Set(rv)
+		}
+		f.taken = true
+		return countPairs(vals), nil
+	case rv.Kind() == reflect.Slice:
+		n := rv.Len()
+		if f.taken || f.appendDef {
+			rv = reflect.AppendSlice(f.v, rv)
+		}
+		f.taken = true
+		f.v.Set(rv)
+		return n, f.checkValue()
+	}
+
 	f.taken = true
 	f.v.Set(rv)
 
-	if rv.Kind() == reflect.Slice {
-		return rv.Len(), nil
-	}
-
-	return 1, nil
-}
-
-func (f *field) transformValue(vals []string) (rv reflect.Value, err error) {
-	var x interface{}
-
-	switch f.v.Interface().(type) {
-	case bool:
-		x, err = strconv.ParseBool(vals[0])
-	case string:
-		x = vals[0]
-	case int:
-		x, err = strconv.Atoi(vals[0])
-	case int64:
-		var _x int
-		_x, err = strconv.Atoi(vals[0])
-		x = int64(_x)
-	case float32:
-		var _x float64
-		_x, err = strconv.ParseFloat(vals[0], 32)
-		x = float32(_x)
-	case float64:
-		x, err = strconv.ParseFloat(vals[0], 64)
-	case time.Duration:
-		x, err = time.ParseDuration(vals[0])
-	case []string:
-		x = vals
-	case []bool:
-		x, err = sliceToBool(vals)
-	case []int:
-		x, err = sliceToInt(vals)
-	case []float32:
-		x, err = sliceToFloat32(vals)
-	case []float64:
-		x, err = sliceToFloat64(vals)
-	case []time.Duration:
-		x, err = sliceToTimeDuration(vals)
-	default:
-		err = fmt.Errorf("field %s has unsupported type %T", f.field.Name, f.v.Interface())
+	return 1, f.checkValue()
+}
+
+func (f *field) transformValue(vals []string) (reflect.Value, error) {
+	t := f.v.Type()
+	switch {
+	case isValueType(t):
+	case t.Kind() == reflect.Slice:
+		return f.transformSlice(t, vals)
+	case t.Kind() == reflect.Map:
+		return f.transformMap(t, vals)
 	}
 
-	return reflect.ValueOf(x), err
+	return f.parseValue(t, vals[0])
 }
 
-func sliceToBool(ss []string) (bools []bool, err error) {
-	var b bool
-	for _, s := range ss {
-		b, err = strconv.ParseBool(s)
+// transformSlice convert values to slice, it stops on first invalid value and omit error if some values already converted
+func (f *field) transformSlice(t reflect.Type, vals []string) (reflect.Value, error) {
+	rv := reflect.MakeSlice(t, 0, len(vals))
+	for _, s := range vals {
+		x, err := f.parseValue(t.Elem(), s)
 		if err != nil {
-			if len(bools) > 0 {
+			// value of plain scalar may belong to next positional argument
+			if rv.Len() > 0 && isScalar(t.Elem()) {
 				err = nil
 			}
-			return
+			return rv, err
 		}
-		bools = append(bools, b)
+		rv = reflect.Append(rv, x)
 	}
 
-	return
+	return rv, nil
 }
 
-func sliceToInt(ss []string) (ints []int, err error) {
-	var i int
-	for _, s := range ss {
-		i, err = strconv.Atoi(s)
-		if err != nil {
-			if len(ints) > 0 {
-				err = nil
+// isScalar report whether type is builtin boolean, number or string
+func isScalar(t reflect.Type) bool {
+	if t.PkgPath() != "" {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return isInt(t.Kind())
+}
+
+// transformMap convert key=value pairs to map, it stops on first value without '=' and omit error if some pairs already converted
+func (f *field) transformMap(t reflect.Type, vals []string) (reflect.Value, error) {
+	rv := reflect.MakeMapWithSize(t, len(vals))
+	for _, s := range vals {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			if rv.Len() > 0 {
+				return rv, nil
 			}
-			return
+			return rv, fmt.Errorf("invalid value %s for '%s', expected key=value", s, f.name)
+		}
+
+		k, err := f.parseValue(t.Key(), kv[0])
+		if err != nil {
+			return rv, fmt.Errorf("invalid key '%s' for '%s': %s", kv[0], f.name, err)
+		}
+
+		v, err := f.parseValue(t.Elem(), kv[1])
+		if err != nil {
+			return rv, fmt.Errorf("invalid value for key '%s' of '%s': %s", kv[0], f.name, err)
 		}
-		ints = append(ints, i)
+
+		rv.SetMapIndex(k, v)
 	}
-	return
+
+	return rv, nil
 }
 
-func sliceToFloat32(ss []string) (ints []float32, err error) {
-	var i float64
-	for _, s := range ss {
-		i, err = strconv.ParseFloat(s, 32)
-		if err != nil {
-			if len(ints) > 0 {
-				err = nil
-			}
-			return
+// checkPairs return error if value of map argument given by one token contains element without '='
+func (f *field) checkPairs(vals []string) error {
+	if f.v.Kind() != reflect.Map || isValueType(f.v.Type()) {
+		return nil
+	}
+	for _, s := range vals {
+		if !strings.Contains(s, "=") {
+			return fmt.Errorf("invalid value %s for '%s', expected key=value", s, f.name)
 		}
-		ints = append(ints, float32(i))
 	}
-	return
+	return nil
 }
 
-func sliceToFloat64(ss []string) (ints []float64, err error) {
-	var i float64
-	for _, s := range ss {
-		i, err = strconv.ParseFloat(s, 64)
-		if err != nil {
-			if len(ints) > 0 {
-				err = nil
-			}
+// countPairs return count of leading key=value pairs
+func countPairs(vals []string) (n int) {
+	for _, s := range vals {
+		if !strings.Contains(s, "=") {
 			return
 		}
-		ints = append(ints, i)
+		n++
 	}
 	return
 }
 
-func sliceToTimeDuration(ss []string) (td []time.Duration, err error) {
-	var d time.Duration
-	for _, s := range ss {
-		d, err = time.ParseDuration(s)
+// parseValue convert string to value of specified type
+func (f *field) parseValue(t reflect.Type, s string) (reflect.Value, error) {
+	if c, ok := lookupConverter(t); ok {
+		x, err := c.parse(s)
 		if err != nil {
-			if len(td) > 0 {
-				err = nil
-			}
-			return
+			return x, fmt.Errorf("invalid value %s for '%s': %s", s, f.name, err)
+		}
+		if t.Kind() == reflect.Ptr && x.Type() != t {
+			p := reflect.New(t.Elem())
+			p.Elem().Set(x)
+			x = p
 		}
-		td = append(td, d)
+		return x, nil
 	}
 
-	return
+	switch {
+	case t == timeType:
+		tm, err := f.parseTime(s)
+		return reflect.ValueOf(tm), err
+	case netPlaceholders[t] != "":
+		return f.parseNetValue(t, s)
+	case t == inputFileType, t == outputFileType:
+		return f.openFile(t, s)
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		x := reflect.New(t)
+		err := x.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return x.Elem(), err
+	case reflect.PtrTo(t).Implements(optionalInterface):
+		x := reflect.New(t)
+		o := x.Interface().(optional)
+		v, err := f.parseValue(o.elemType(), s)
+		if err != nil {
+			return x.Elem(), err
+		}
+		o.assign(v)
+		return x.Elem(), nil
+	case t.Kind() == reflect.Ptr:
+		x := reflect.New(t.Elem())
+		v, err := f.parseValue(t.Elem(), s)
+		if err != nil {
+			return x, err
+		}
+		x.Elem().Set(v)
+		return x, nil
+	}
+
+	x := reflect.New(t).Elem()
+
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		x.SetInt(int64(d))
+		return x, err
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		x.SetBool(b)
+		return x, err
+	case reflect.String:
+		x.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, intBase(s), t.Bits())
+		if err != nil {
+			return x, f.intError(t, s, err)
+		}
+		x.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, intBase(s), t.Bits())
+		if err != nil {
+			return x, f.intError(t, s, err)
+		}
+		x.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, t.Bits())
+		x.SetFloat(fl)
+		return x, err
+	default:
+		return x, fmt.Errorf("field %s has unsupported type %s", f.field.Name, f.v.Type())
+	}
+
+	return x, nil
+}
+
+// parseTime parse time by layout of field, also it understands relative values:
+// now, today, yesterday, tomorrow and durations from now, like -2h, +30m or -7d
+func (f *field) parseTime(s string) (time.Time, error) {
+	now := Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if len(s) > 1 && (s[0] == '-' || s[0] == '+') {
+		if d, err := ParseDuration(s); err == nil {
+			return now.Add(time.Duration(d)), nil
+		}
+	}
+
+	tm, err := time.ParseInLocation(f.timeLayout(), s, now.Location())
+	if err != nil {
+		return tm, fmt.Errorf("invalid time %s for '%s', expected layout %s", s, f.name, f.timeLayout())
+	}
+
+	return tm, nil
+}
+
+// isRelativeTime report whether value is negative duration given to time argument
+func (f *field) isRelativeTime(s string) bool {
+	if elemType(f.v.Type()) != timeType || len(s) < 2 || s[0] != '-' {
+		return false
+	}
+	_, err := ParseDuration(s)
+	return err == nil
+}
+
+// timeLayout return layout of time value, by default it is RFC3339
+func (f *field) timeLayout() string {
+	if f.layout == "" {
+		return time.RFC3339
+	}
+	return f.layout
+}
+
+func (f *field) intError(t reflect.Type, s string, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return fmt.Errorf("value %s for '%s' is out of range of %s", s, f.name, t.Kind())
+	}
+	return fmt.Errorf("invalid value %s for '%s', expected %s", s, f.name, t.Kind())
+}
+
+// intBase return base for parse integer, base 0 understands 0x, 0o, 0b prefixes and _ separators,
+// but it interprets leading zero as octal, so decimal values with leading zeros parsed by base 10
+func intBase(s string) int {
+	s = strings.TrimLeft(s, "+-")
+	if len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9' {
+		return 10
+	}
+	return 0
+}
+
+// isValueType report whether type parsed from single value by itself,
+// such types are not treated as internal structs or slices of values
+func isValueType(t reflect.Type) bool {
+	if _, ok := lookupConverter(t); ok {
+		return true
+	}
+	if _, ok := optionalElem(t); ok {
+		return true
+	}
+	if _, ok := netPlaceholders[t]; ok {
+		return true
+	}
+	if t == inputFileType || t == outputFileType {
+		return true
+	}
+	if t.Kind() == reflect.Ptr && (t.Elem().Kind() != reflect.Struct || isValueType(t.Elem())) {
+		return true
+	}
+	return isValueInterface(t) || t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func isValueInterface(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Implements(valueInterface) || reflect.PtrTo(t).Implements(valueInterface)
+}
+
+func isInt(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,137 @@
+package argum
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxResponseDepth limits nesting of response files
+const maxResponseDepth = 10
+
+// expandResponseFiles replace `@file` arguments by arguments read from file, arguments after `--` are kept as is
+func expandResponseFiles(args []string) ([]string, error) {
+	var expanded []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+
+		if !isResponseFile(arg) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		fileArgs, err := readResponseFile(arg[1:], 0)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, fileArgs...)
+	}
+	return expanded, nil
+}
+
+// isResponseFile report whether argument is reference to response file
+func isResponseFile(arg string) bool {
+	return len(arg) > 1 && arg[0] == '@'
+}
+
+// readResponseFile read arguments from file, each line is split to shell-style tokens, nested response files are expanded
+func readResponseFile(filename string, depth int) ([]string, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed read response file, %s", err)
+	}
+	defer fd.Close()
+
+	var args []string
+	var terminated bool
+
+	scanner := bufio.NewScanner(fd)
+	for line := 1; scanner.Scan(); line++ {
+		tokens, err := splitShellLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", filename, line, err)
+		}
+
+		for _, token := range tokens {
+			terminated = terminated || token == "--"
+			if terminated || !isResponseFile(token) {
+				args = append(args, token)
+				continue
+			}
+
+			if depth+1 >= maxResponseDepth {
+				return nil, fmt.Errorf("%s:%d: response files nested too deeply", filename, line)
+			}
+
+			nested, err := readResponseFile(token[1:], depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", filename, line, err)
+			}
+			args = append(args, nested...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return args, nil
+}
+
+// splitShellLine split line to tokens by spaces, considering single and double quotes,
+// backslash escapes only quote and backslash inside double quotes, so Windows paths are kept as is
+func splitShellLine(line string) (tokens []string, err error) {
+	var token strings.Builder
+	var inToken bool
+	var quote rune
+	var escape bool
+
+	for _, r := range line {
+		switch {
+		case escape:
+			if r != '"' && r != '\\' {
+				token.WriteRune('\\')
+			}
+			token.WriteRune(r)
+			escape = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				token.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escape = true
+			default:
+				token.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inToken = true
+		case r == ' ' || r == '\t' || r == '\r':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+
+	return
+}
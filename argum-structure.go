@@ -19,6 +19,15 @@ type structure struct {
 }
 
 func prepareStructure(i interface{}) (*structure, error) {
+	s, err := prepareFields(i)
+	if err != nil {
+		return s, err
+	}
+	return s, s.checkReferences()
+}
+
+// prepareFields prepare fields of structure and nested structures
+func prepareFields(i interface{}) (*structure, error) {
 	s := newStructure(i)
 
 	// prepare fields
@@ -65,16 +74,41 @@ func newStructure(i interface{}) *structure {
 	return s
 }
 
+// parse arguments and validate result
+func (s *structure) parse(args []string) error {
+	if _, err := s.parseArgs(args); err != nil {
+		return err
+	}
+	if err := s.validate(); err != nil {
+		return err
+	}
+	return s.createOutputFiles()
+}
+
 func (s *structure) parseArgs(args []string) (i int, err error) {
 	for i = 0; i < len(args); i++ {
 		arg := args[i]
 		if arg == "--" {
-			continue
+			if f, ok := s.restField(); ok {
+				f.setRest(args[i+1:])
+				return len(args), s.checkRequired()
+			}
+			n, err := s.setOperands(args[i+1:])
+			return i + 1 + n, err
+		}
+
+		if POSIXMode && s.isOperand(arg) {
+			n, err := s.setOperands(args[i:])
+			return i + n, err
 		}
 
 		if matchSortBooleans(arg) {
 			shortargs, err := s.splitShortBooleans(arg)
 			if err != nil {
+				if f, ok := s.restField(); ok {
+					f.setRest(args[i:])
+					return len(args), s.checkRequired()
+				}
 				return i, err
 			}
 			if _, err = s.parseArgs(shortargs); err != nil {
@@ -89,19 +123,65 @@ func (s *structure) parseArgs(args []string) (i int, err error) {
 
 		key, vals := splitArg(arg)
 
+		if key, err = s.canonicalKey(key); err != nil {
+			if f, ok := s.restField(); ok {
+				f.setRest(args[i:])
+				return len(args), s.checkRequired()
+			}
+			return i, err
+		}
+
 		f, ok := s.lookupField(key)
 		if !ok {
+			if f, ok := s.restField(); ok {
+				f.setRest(args[i:])
+				return len(args), s.checkRequired()
+			}
 			return i, fmt.Errorf("unexpected argument '%s'", args[i])
 		}
 
+		// value of field given twice is kept to restore it if first occurrence wins
+		var keep reflect.Value
+		if f.taken && !f.repeatable() && f.matchKey(key) {
+			policy := f.duplicatePolicy()
+
+			// negated form appended after positive one, or vice versa, overrides value
+			if policy == DuplicateError && f.negatable && (key == f.negLong()) != (f.given == f.negLong()) {
+				policy = DuplicateLast
+			}
+
+			switch policy {
+			case DuplicateError:
+				return i, fmt.Errorf("%s given twice", key)
+			case DuplicateFirst:
+				keep = reflect.New(f.v.Type()).Elem()
+				keep.Set(f.v)
+			}
+		}
+		if f.matchKey(key) {
+			f.given = key
+		}
+
 		var n int
 		var x int
 		var next []string
 
-		if len(vals) == 0 && i+1 < len(args) {
+		if sep := f.separator(); sep != "," {
+			if len(vals) > 0 {
+				vals = splitValuesSep(strings.Join(vals, ","), sep)
+			}
+			if len(vals) == 0 && i+1 < len(args) {
+				next, x = s.nextValues(args[i+1:], sep)
+			}
+		} else if len(vals) == 0 && i+1 < len(args) {
 			next, x = s.getNextValues(args[i+1:])
 		}
 
+		// relative time like -2h looks like short option, but it is value of time argument
+		if len(vals) == 0 && len(next) == 0 && i+1 < len(args) && f.isRelativeTime(args[i+1]) {
+			next, x = args[i+1:i+2], 1
+		}
+
 		switch {
 		case f.oneof:
 			n, err = f.setStruct(args[i:])
@@ -109,7 +189,13 @@ func (s *structure) parseArgs(args []string) (i int, err error) {
 			n, err = f.setStruct(args[i:])
 		case f.cmd:
 			n, err = f.setStruct(args[i+1:])
-		case f.v.Kind() == reflect.Bool:
+		case f.count:
+			if len(vals) > 0 {
+				_, err = f.setValue(vals...)
+			} else {
+				f.increment()
+			}
+		case f.isBool():
 			n, err = f.setBool(key, vals, next)
 		case f.pos:
 			if len(vals) > 0 {
@@ -132,15 +218,27 @@ func (s *structure) parseArgs(args []string) (i int, err error) {
 			}
 		default:
 			if len(vals) > 0 {
-				_, err = f.setValue(vals...)
+				if err = f.checkPairs(vals); err == nil {
+					_, err = f.setValue(vals...)
+				}
 			} else {
-				n, err = f.setValue(next...)
+				// values of first next argument split by separator belong to one token
+				if x == 1 && len(next) > 1 {
+					err = f.checkPairs(next)
+				}
+				if err == nil {
+					n, err = f.setValue(next...)
+				}
 				if n > x {
 					n = x
 				}
 			}
 		}
 
+		if keep.IsValid() {
+			f.v.Set(keep)
+		}
+
 		i += n
 
 		if (f.oneof || f.cmd || f.emb) && err != nil && i+1 < len(args) {
@@ -152,13 +250,88 @@ func (s *structure) parseArgs(args []string) (i int, err error) {
 		}
 	}
 
+	return i, s.checkRequired()
+}
+
+// checkRequired return error if some of required arguments is not set
+func (s *structure) checkRequired() error {
 	for _, f := range s.fields {
 		if f.req && !f.taken {
-			return i, fmt.Errorf("required argument '%s' not set", f.name)
+			return fmt.Errorf("required argument '%s' not set", f.name)
 		}
 	}
+	return nil
+}
 
-	return
+// restField return field receiving arguments after `--` or unexpected tail
+func (s *structure) restField() (*field, bool) {
+	for _, f := range s.fields {
+		if f.rest {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// isOperand report whether argument is neither option nor command
+func (s *structure) isOperand(arg string) bool {
+	if matchLong(arg) || matchShort(arg) || matchSortBooleans(arg) {
+		return false
+	}
+	f, ok := s.lookupField(arg)
+	return !ok || f.pos
+}
+
+// setOperands assign arguments to not taken positionals as is, without recognizing options
+func (s *structure) setOperands(args []string) (n int, err error) {
+	defer s.selectEmbedded()
+
+	for _, f := range s.flatFields() {
+		if n >= len(args) {
+			break
+		}
+		if f.taken || !f.pos || f.cmd || f.rest {
+			continue
+		}
+
+		var x int
+		x, err = f.setValue(args[n:]...)
+		if err != nil {
+			return
+		}
+		if x == 0 {
+			x = 1
+		}
+		n += x
+	}
+
+	if n < len(args) {
+		f, ok := s.restField()
+		if !ok {
+			return n, fmt.Errorf("unexpected argument '%s'", args[n])
+		}
+		f.setRest(args[n:])
+		n = len(args)
+	}
+
+	return n, s.checkRequired()
+}
+
+// selectEmbedded select embedded structures which fields are set
+func (s *structure) selectEmbedded() {
+	for _, f := range s.fields {
+		if !f.emb {
+			continue
+		}
+
+		f.s.selectEmbedded()
+		for _, ef := range f.s.fields {
+			if ef.taken {
+				f.selectStruct()
+				break
+			}
+		}
+	}
 }
 
 func (s *structure) splitShortBooleans(arg string) (shorts []string, err error) {
@@ -176,7 +349,7 @@ func (s *structure) splitShortBooleans(arg string) (shorts []string, err error)
 
 func (s *structure) recShortBoolExists(arg string) bool {
 	for _, f := range s.fields {
-		if f.short == arg && f.v.Kind() == reflect.Bool {
+		if f.short == arg && (f.isBool() || f.count) {
 			return true
 		}
 	}
@@ -217,6 +390,11 @@ func (s *structure) recursiveArgExists(arg string) (*field, bool) {
 }
 
 func (s *structure) getNextValues(osArgs []string) (vals []string, n int) {
+	return s.nextValues(osArgs, ",")
+}
+
+// nextValues collect values following argument, first value is split by separator
+func (s *structure) nextValues(osArgs []string, sep string) (vals []string, n int) {
 	for i, arg := range osArgs {
 		var ok bool
 
@@ -229,8 +407,8 @@ func (s *structure) getNextValues(osArgs []string) (vals []string, n int) {
 			// _, ok = s.lookupShortField(arg)
 		case arg == "--":
 			ok = true
-		case strings.Contains(arg, ",") && i == 0:
-			vals = append(vals, splitValues(arg)...)
+		case sep != "" && strings.Contains(arg, sep) && i == 0:
+			vals = append(vals, splitValuesSep(arg, sep)...)
 			n = 1
 			ok = true
 		default:
@@ -257,14 +435,14 @@ func (s *structure) getNextValues(osArgs []string) (vals []string, n int) {
 func (s *structure) lookupField(arg string) (*field, bool) {
 	// short and log options
 	for _, f := range s.fields {
-		if !f.taken && (f.short != "" && f.short == arg || f.long != "" && f.long == arg) {
+		if f.matchKey(arg) {
 			return f, true
 		}
 	}
 
 	// positionals
 	for _, f := range s.fields {
-		if !f.taken && f.pos && !f.cmd {
+		if !f.taken && f.pos && !f.cmd && !f.rest {
 			return f, true
 		}
 	}
@@ -278,7 +456,7 @@ func (s *structure) lookupField(arg string) (*field, bool) {
 
 	// commands
 	for _, f := range s.fields {
-		if !f.taken && f.cmd && f.name == arg {
+		if !f.taken && f.cmd && equalName(f.name, arg) {
 			return f, true
 		}
 	}
@@ -286,6 +464,37 @@ func (s *structure) lookupField(arg string) (*field, bool) {
 	return nil, false
 }
 
+// canonicalKey return declared form of long option, resolving case and unambiguous abbreviation
+func (s *structure) canonicalKey(arg string) (string, error) {
+	if !matchLong(arg) {
+		return arg, nil
+	}
+
+	var names []string
+	for _, f := range s.flatFields() {
+		for _, name := range []string{f.long, f.negLong()} {
+			if name == "" {
+				continue
+			}
+			if equalName(name, arg) {
+				return name, nil
+			}
+			if AllowAbbrev && hasNamePrefix(name, arg) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	switch len(names) {
+	case 0:
+		return arg, nil
+	case 1:
+		return names[0], nil
+	}
+
+	return arg, fmt.Errorf("ambiguous option '%s': %s", arg, strings.Join(names, ", "))
+}
+
 func (s *structure) lookupLongField(arg string) (*field, bool) {
 	for _, f := range s.fields {
 		if f.long == arg && !f.taken {
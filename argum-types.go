@@ -0,0 +1,448 @@
+package argum
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type converter struct {
+	parse       func(string) (reflect.Value, error)
+	placeholder string
+}
+
+var converters = make(map[reflect.Type]converter)
+
+// RegisterType register parse function for type T, then fields of types T, *T and []T are supported,
+// placeholder is name of value shown in usage and help. It should be called before Parse, e.g. in init
+func RegisterType[T any](parse func(string) (T, error), placeholder string) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	converters[t] = converter{
+		parse: func(s string) (reflect.Value, error) {
+			x, err := parse(s)
+			return reflect.ValueOf(&x).Elem(), err
+		},
+		placeholder: placeholder,
+	}
+}
+
+// lookupConverter return converter registered for type or for type pointed to
+func lookupConverter(t reflect.Type) (converter, bool) {
+	if c, ok := converters[t]; ok {
+		return c, true
+	}
+
+	if t.Kind() == reflect.Ptr {
+		c, ok := converters[t.Elem()]
+		return c, ok
+	}
+
+	return converter{}, false
+}
+
+// Optional is a field type that reports whether value was set from command line,
+// default value is available by Get, but IsSet returns false for it
+type Optional[T any] struct {
+	value T
+	set   bool
+}
+
+// IsSet report whether value was set from command line
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// Get return value, if value was not set it is default or zero value
+func (o Optional[T]) Get() T {
+	return o.value
+}
+
+type optional interface {
+	elemType() reflect.Type
+	assign(v reflect.Value)
+	unset()
+}
+
+func (o *Optional[T]) elemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (o *Optional[T]) assign(v reflect.Value) {
+	o.value = v.Interface().(T)
+	o.set = true
+}
+
+func (o *Optional[T]) unset() {
+	o.set = false
+}
+
+// optionalElem return type of value contained in Optional
+func optionalElem(t reflect.Type) (reflect.Type, bool) {
+	if !reflect.PtrTo(t).Implements(optionalInterface) {
+		return nil, false
+	}
+	return reflect.New(t).Interface().(optional).elemType(), true
+}
+
+var (
+	addrType         = reflect.TypeOf(netip.Addr{})
+	addrPortType     = reflect.TypeOf(netip.AddrPort{})
+	prefixType       = reflect.TypeOf(netip.Prefix{})
+	ipType           = reflect.TypeOf(net.IP{})
+	ipNetType        = reflect.TypeOf(net.IPNet{})
+	hardwareAddrType = reflect.TypeOf(net.HardwareAddr{})
+	urlType          = reflect.TypeOf(url.URL{})
+)
+
+// netPlaceholders contains supported network types and names of their values in usage and help
+var netPlaceholders = map[reflect.Type]string{
+	addrType:         "ip",
+	addrPortType:     "ip:port",
+	prefixType:       "ip/bits",
+	ipType:           "ip",
+	ipNetType:        "ip/bits",
+	hardwareAddrType: "mac",
+	urlType:          "url",
+}
+
+// parseNetValue convert string to value of network type
+func (f *field) parseNetValue(t reflect.Type, s string) (reflect.Value, error) {
+	switch t {
+	case addrType:
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return reflect.ValueOf(addr), fmt.Errorf("invalid IP address %s for '%s'", s, f.name)
+		}
+		return reflect.ValueOf(addr), nil
+	case addrPortType:
+		addr, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return reflect.ValueOf(addr), fmt.Errorf("invalid address %s for '%s', expected ip:port", s, f.name)
+		}
+		return reflect.ValueOf(addr), nil
+	case prefixType:
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return reflect.ValueOf(prefix), fmt.Errorf("invalid network prefix %s for '%s', expected ip/bits", s, f.name)
+		}
+		return reflect.ValueOf(prefix), nil
+	case ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return reflect.ValueOf(ip), fmt.Errorf("invalid IP address %s for '%s'", s, f.name)
+		}
+		return reflect.ValueOf(ip), nil
+	case ipNetType:
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return reflect.ValueOf(net.IPNet{}), fmt.Errorf("invalid network %s for '%s', expected ip/bits", s, f.name)
+		}
+		return reflect.ValueOf(*ipnet), nil
+	case hardwareAddrType:
+		mac, err := net.ParseMAC(s)
+		if err != nil {
+			return reflect.ValueOf(mac), fmt.Errorf("invalid hardware address %s for '%s'", s, f.name)
+		}
+		return reflect.ValueOf(mac), nil
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return reflect.ValueOf(url.URL{}), fmt.Errorf("invalid URL %s for '%s': %s", s, f.name, err)
+		}
+		if len(f.schemes) > 0 && !contains(f.schemes, u.Scheme) {
+			return reflect.ValueOf(*u), fmt.Errorf("invalid URL %s for '%s', scheme should be one of %s", s, f.name, f.schemes)
+		}
+		return reflect.ValueOf(*u), nil
+	}
+
+	return reflect.New(t).Elem(), fmt.Errorf("field %s has unsupported type %s", f.field.Name, t)
+}
+
+// ByteSize is size in bytes, it understands SI and IEC suffixes, like 10MB or 512MiB
+type ByteSize uint64
+
+var byteSizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"EiB", 1 << 60}, {"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"EB", 1e18}, {"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parse size in bytes, suffixes are case insensitive: k, kB, KiB, M, MB, MiB and so on up to exabytes
+func ParseByteSize(s string) (ByteSize, error) {
+	num := strings.TrimRightFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	unit := strings.TrimSpace(s[len(num):])
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %s", s)
+	}
+
+	size, ok := byteSizeUnit(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %s, unknown unit %s", s, unit)
+	}
+
+	if strings.Contains(num, ".") {
+		x, err := strconv.ParseFloat(num, 64)
+		if err != nil || x*float64(size) >= math.MaxUint64 {
+			return 0, fmt.Errorf("invalid byte size %s", s)
+		}
+		return ByteSize(x * float64(size)), nil
+	}
+
+	x, err := strconv.ParseUint(num, 10, 64)
+	if err != nil || x > math.MaxUint64/size {
+		return 0, fmt.Errorf("invalid byte size %s", s)
+	}
+
+	return ByteSize(x * size), nil
+}
+
+func byteSizeUnit(unit string) (uint64, bool) {
+	unit = strings.ToLower(unit)
+	if unit == "" {
+		return 1, true
+	}
+	if !strings.HasSuffix(unit, "b") {
+		unit += "b"
+	}
+
+	for _, u := range byteSizeUnits {
+		if strings.ToLower(u.name) == unit {
+			return u.size, true
+		}
+	}
+
+	return 0, false
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) (err error) {
+	*b, err = ParseByteSize(string(text))
+	return
+}
+
+// String return size with the largest unit that divides it without remainder
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+
+	unit := byteSizeUnits[len(byteSizeUnits)-1]
+	for _, u := range byteSizeUnits {
+		if uint64(b)%u.size == 0 && u.size > unit.size {
+			unit = u
+		}
+	}
+
+	return strconv.FormatUint(uint64(b)/unit.size, 10) + unit.name
+}
+
+// Duration is time.Duration which also understands days and weeks, like 7d or 2w3d12h
+type Duration time.Duration
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// ParseDuration parse duration as time.ParseDuration does, but also accepts leading days and weeks
+func ParseDuration(s string) (Duration, error) {
+	str := s
+
+	var neg bool
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %s", str)
+	}
+
+	var d time.Duration
+	for {
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 || s[i] != 'd' && s[i] != 'w' {
+			break
+		}
+
+		x, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", str)
+		}
+
+		unit := day
+		if s[i] == 'w' {
+			unit = week
+		}
+		d += time.Duration(x * float64(unit))
+		s = s[i+1:]
+	}
+
+	if s != "" {
+		rest, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", str)
+		}
+		d += rest
+	}
+
+	if neg {
+		d = -d
+	}
+
+	return Duration(d), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	*d, err = ParseDuration(string(text))
+	return
+}
+
+// String return duration with weeks and days, like 1w2d12h
+func (d Duration) String() string {
+	td := time.Duration(d)
+
+	var s string
+	if td < 0 {
+		s = "-"
+		td = -td
+	}
+
+	if w := td / week; w > 0 {
+		s += strconv.FormatInt(int64(w), 10) + "w"
+		td -= w * week
+	}
+	if n := td / day; n > 0 {
+		s += strconv.FormatInt(int64(n), 10) + "d"
+		td -= n * day
+	}
+
+	if td == 0 && s != "" && s != "-" {
+		return s
+	}
+
+	rest := td.String()
+	if strings.HasSuffix(rest, "m0s") {
+		rest = strings.TrimSuffix(rest, "0s")
+	}
+	if strings.HasSuffix(rest, "h0m") {
+		rest = strings.TrimSuffix(rest, "0m")
+	}
+
+	return s + rest
+}
+
+// InputFile is file opened for reading during parsing, value "-" means stdin
+type InputFile struct {
+	*os.File
+}
+
+// OutputFile is file created for writing after successful parsing, value "-" means stdout
+type OutputFile struct {
+	*os.File
+	path string
+}
+
+var (
+	inputFileType  = reflect.TypeOf(InputFile{})
+	outputFileType = reflect.TypeOf(OutputFile{})
+	openedFiles    []*os.File
+)
+
+// CloseFiles close all files opened during parsing, stdin and stdout are not closed
+func CloseFiles() (err error) {
+	for _, file := range openedFiles {
+		if e := file.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	openedFiles = nil
+	return
+}
+
+// createOutputFiles create output files of structure and its selected nested structures
+func (s *structure) createOutputFiles() error {
+	for _, f := range s.fields {
+		if f.s != nil {
+			if !f.s.taken {
+				continue
+			}
+			if err := f.s.createOutputFiles(); err != nil {
+				return err
+			}
+			// value of nested structure is copied to field again, if it is not a pointer
+			f.selectStruct()
+			continue
+		}
+
+		if elemType(f.v.Type()) != outputFileType {
+			continue
+		}
+
+		for _, v := range elemValues(f.v) {
+			if !v.CanAddr() {
+				continue
+			}
+			out := v.Addr().Interface().(*OutputFile)
+			if out.File != nil || out.path == "" {
+				continue
+			}
+
+			file, err := os.Create(out.path)
+			if err != nil {
+				return fmt.Errorf("failed open file for '%s': %s", f.name, err)
+			}
+			openedFiles = append(openedFiles, file)
+			out.File = file
+		}
+	}
+	return nil
+}
+
+// isFileType report whether type is InputFile or OutputFile, or slice or pointer of them
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == inputFileType || t == outputFileType
+}
+
+// openFile open file for InputFile or OutputFile field
+func (f *field) openFile(t reflect.Type, s string) (reflect.Value, error) {
+	x := reflect.New(t).Elem()
+
+	var file *os.File
+	var err error
+
+	switch {
+	case s == "-" && t == inputFileType:
+		file = os.Stdin
+	case s == "-":
+		file = os.Stdout
+	case t == inputFileType:
+		file, err = os.Open(s)
+	default:
+		// output file is created after successful parsing, so parse errors do not truncate it
+		return reflect.ValueOf(OutputFile{path: s}), nil
+	}
+	if err != nil {
+		return x, fmt.Errorf("failed open file for '%s': %s", f.name, err)
+	}
+
+	if s != "-" {
+		openedFiles = append(openedFiles, file)
+	}
+
+	x.Field(0).Set(reflect.ValueOf(file))
+	return x, nil
+}
@@ -2,11 +2,11 @@ package argum
 
 import (
 	"bytes"
+	"encoding"
 	"fmt"
 	"io"
 	"reflect"
 	"strings"
-	"time"
 )
 
 const leftColLength = 26
@@ -18,6 +18,16 @@ var (
 	newline = []byte(fmt.Sprintf("\n%26s", " "))
 )
 
+// Exampler is implemented by structures that provide usage examples, they are output at the end of help
+type Exampler interface {
+	Examples() []string
+}
+
+// Epiloger is implemented by structures that provide a text output after all help sections
+type Epiloger interface {
+	Epilog() string
+}
+
 func (s *structure) appendHelpOptions() {
 	s.fields = append(s.fields, helparg)
 	if Version != "" {
@@ -34,6 +44,7 @@ func (s *structure) writeUsageHelp(w io.Writer) {
 	s.writeUsage(w)
 	s.appendHelpOptions()
 	s.writeHelp(w)
+	s.writeEpilog(w, "")
 }
 
 func (s *structure) writeUsage(w io.Writer) {
@@ -49,11 +60,17 @@ func (s *structure) writeUsage(w io.Writer) {
 		usage = append(usage, "[-"+shortbooleans+"]")
 	}
 
+	groups := make(map[string]bool)
 	for _, f := range other {
-
-		if f.pos {
+		switch {
+		case f.xor != "":
+			if !groups[f.xor] {
+				groups[f.xor] = true
+				usage = append(usage, usageGroup(f.xor, other))
+			}
+		case f.pos:
 			usage = append(usage, f.usagePos())
-		} else {
+		default:
 			usage = append(usage, f.usageOpt())
 		}
 	}
@@ -100,6 +117,24 @@ func (s *structure) writeHelp(w io.Writer) {
 	}
 }
 
+// writeEpilog write examples and epilog of structure if it implements Exampler or Epiloger
+func (s *structure) writeEpilog(w io.Writer, prefix string) {
+	if e, ok := s.i.(Exampler); ok {
+		if examples := e.Examples(); len(examples) > 0 {
+			fmt.Fprintf(w, "\n%sexamples:\n", prefix)
+			for _, example := range examples {
+				fmt.Fprintf(w, "%s  %s\n", prefix, example)
+			}
+		}
+	}
+
+	if e, ok := s.i.(Epiloger); ok {
+		if epilog := e.Epilog(); epilog != "" {
+			fmt.Fprintf(w, "\n%s%s\n", prefix, epilog)
+		}
+	}
+}
+
 func (s *structure) splitFieldsUsage() (commands, shortbooleans, other []*field) {
 	for _, f := range s.fields {
 		switch {
@@ -110,7 +145,7 @@ func (s *structure) splitFieldsUsage() (commands, shortbooleans, other []*field)
 			other = append(other, ot...)
 		case f.cmd:
 			commands = append(commands, f)
-		case f.shortboolean:
+		case f.shortboolean && f.xor == "":
 			shortbooleans = append(shortbooleans, f)
 		default:
 			other = append(other, f)
@@ -142,30 +177,86 @@ func (s *structure) splitFieldsHelp() (oneof, commands, pos, opt []*field) {
 	return
 }
 
+// usageGroup return usage of mutually exclusive arguments, like [--json | --yaml]
+func usageGroup(group string, fields []*field) string {
+	var names []string
+	var req bool
+	for _, f := range fields {
+		if f.xor != group {
+			continue
+		}
+
+		switch {
+		case f.pos:
+			names = append(names, f.usagePosName())
+		case f.isBool():
+			names = append(names, f.displayName())
+		default:
+			names = append(names, f.displayName()+"="+f.valueType())
+		}
+		req = req || f.xorreq
+	}
+
+	if req {
+		return "(" + strings.Join(names, " | ") + ")"
+	}
+
+	return "[" + strings.Join(names, " | ") + "]"
+}
+
 func (f *field) usageOpt() string {
+	name := f.usageOptName()
+
+	if f.req {
+		return name
+	}
+
+	return fmt.Sprintf("[%s]", name)
+}
+
+func (f *field) usageOptName() string {
 	name := f.short
 	if name == "" {
-		name = f.long
+		name = f.longUsage()
 	}
 
-	val := f.valueType()
-	if val == "" {
-		return name
+	if val := f.valueType(); val != "" {
+		name = fmt.Sprintf("%s=%s", name, val)
+	}
+
+	return name
+}
+
+func (f *field) usagePos() string {
+	if f.rest {
+		name := f.name
+		if f.placeholder != "" {
+			name = f.placeholder
+		}
+		return fmt.Sprintf("[-- %s...]", name)
 	}
 
+	name := f.usagePosName()
+
 	if f.req {
-		return fmt.Sprintf("%s=%s", name, val)
+		return name
 	}
 
-	return fmt.Sprintf("[%s=%s]", name, val)
+	return fmt.Sprintf("[%s]", name)
 }
 
-func (f *field) usagePos() string {
+func (f *field) usagePosName() string {
 	var name string
 
-	if len(f.variants) > 0 {
+	switch {
+	case len(f.variants) > 0:
 		name = strings.Join(f.variants, "|")
-	} else {
+	case f.placeholder != "":
+		name = f.placeholder
+		if f.v.Kind() == reflect.Slice {
+			name += "..."
+		}
+	default:
 		switch f.v.Kind() {
 		case reflect.Slice:
 			// name = fmt.Sprintf("<%s...>", f.field.Name)
@@ -176,49 +267,88 @@ func (f *field) usagePos() string {
 		}
 	}
 
-	if f.req {
-		return name
-	}
-
-	return fmt.Sprintf("[%s]", name)
+	return name
 }
 
 func (f *field) valueType() string {
-	if !f.v.CanSet() {
+	if !f.v.CanSet() || f.negatable || f.count {
 		return ""
 	}
 
+	if f.placeholder != "" {
+		if f.v.Kind() == reflect.Slice {
+			return f.placeholder + "..."
+		}
+		return f.placeholder
+	}
+
 	if len(f.variants) > 0 {
 		return "[" + strings.Join(f.variants, "|") + "]"
 	}
 
-	switch f.v.Interface().(type) {
-	case string:
-		return "<s>"
-	case int:
-		return "<n>"
-	case float32:
-		return "<float>"
-	case float64:
-		return "<float>"
-	case time.Duration:
-		return "<time>"
-
-	case []string:
-		return "[s...]"
-	case []int:
-		return "[n...]"
-	case []float32:
-		return "[float...]"
-	case []float64:
-		return "[float...]"
-	case []time.Duration:
-		return "[time...]"
-	case bool:
-		return fmt.Sprintf("true/false")
-	}
-
-	return ""
+	t := f.v.Type()
+	switch {
+	case f.isBool():
+		return "true/false"
+	case t.Kind() == reflect.Slice && !isValueType(t):
+		return "[" + f.typePlaceholder(t.Elem()) + "...]"
+	case t.Kind() == reflect.Map && !isValueType(t):
+		return "<key=value>"
+	}
+
+	return "<" + f.typePlaceholder(t) + ">"
+}
+
+// typePlaceholder return short name of value type, it used if field has not placeholder tag
+func (f *field) typePlaceholder(t reflect.Type) string {
+	if c, ok := lookupConverter(t); ok {
+		return c.placeholder
+	}
+	if t.Kind() == reflect.Ptr {
+		return f.typePlaceholder(t.Elem())
+	}
+	if elem, ok := optionalElem(t); ok {
+		return f.typePlaceholder(elem)
+	}
+
+	switch {
+	case t == durationType, t == reflect.TypeOf(Duration(0)):
+		return "time"
+	case t == reflect.TypeOf(ByteSize(0)):
+		return "size"
+	case t == timeType:
+		return f.timeLayout()
+	case netPlaceholders[t] != "":
+		return netPlaceholders[t]
+	case t == inputFileType, t == outputFileType:
+		return "file"
+	case reflect.PtrTo(t).Implements(typerInterface):
+		return reflect.New(t).Interface().(typer).Type()
+	case isValueType(t):
+		return "value"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "s"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "n"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "true/false"
+	}
+
+	return "value"
+}
+
+// longUsage return long key as it displayed in usage and help, negatable arguments shown as --[no-]name
+func (f *field) longUsage() string {
+	if f.negatable {
+		return "--[no-]" + f.long[2:]
+	}
+	return f.long
 }
 
 func (f *field) writeHelpString(w io.Writer, prefix string) {
@@ -239,6 +369,8 @@ func (f *field) writeHelpString(w io.Writer, prefix string) {
 			subf.writeHelpString(w, strings.Repeat(" ", len(prefix)+2))
 		}
 
+		f.s.writeEpilog(w, strings.Repeat(" ", len(prefix)+2))
+
 	case f.pos:
 		f.writePositional(w, prefix)
 		f.writeHelp(w)
@@ -274,7 +406,7 @@ func (f *field) writeOption(w io.Writer, prefix string) {
 		if f.short != "" {
 			left += ","
 		}
-		left += " " + f.long
+		left += " " + f.longUsage()
 	}
 
 	if val := f.valueType(); val != "" {
@@ -294,12 +426,12 @@ func (f *field) writeHelp(w io.Writer) {
 	n := writeWordWrap(w, f.help)
 
 	//write default
-	if f.def != "" {
+	if d := f.defaultValue(); d != "" {
 		var def string
 		if f.cmd {
 			def = " [default]"
 		} else {
-			def = " [default: " + f.def + "]"
+			def = " [default: " + d + "]"
 		}
 		if n+len(def) > rightColLength {
 			w.Write(newline)
@@ -312,8 +444,43 @@ func (f *field) writeHelp(w io.Writer) {
 		if n+len(variants) > rightColLength {
 			w.Write(newline)
 		}
-		writeWordWrap(w, variants)
+		n = writeWordWrap(w, variants)
+	}
+
+	for _, c := range f.constraints() {
+		c = " [" + c + "]"
+		if n+len(c) > rightColLength {
+			w.Write(newline)
+		}
+		n = writeWordWrap(w, c)
+	}
+}
+
+// defaultValue return default value for output to help, if default tag is not set,
+// then non-zero value of field implementing Value or encoding.TextMarshaler is used
+func (f *field) defaultValue() string {
+	if f.def != "" || f.cmd || !f.v.IsValid() || f.v.IsZero() {
+		return f.def
+	}
+
+	if f.isValue() {
+		return f.value().String()
+	}
+
+	m, ok := f.v.Interface().(encoding.TextMarshaler)
+	if !ok && f.v.CanAddr() {
+		m, ok = f.v.Addr().Interface().(encoding.TextMarshaler)
 	}
+	if !ok {
+		return ""
+	}
+
+	text, err := m.MarshalText()
+	if err != nil {
+		return ""
+	}
+
+	return string(text)
 }
 
 //write text by words and return length of last line
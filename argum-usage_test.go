@@ -62,3 +62,65 @@ func TestHelp(t *testing.T) {
 		t.Fatalf("error: %s", err)
 	}
 }
+
+func TestPlaceholder(t *testing.T) {
+	var args struct {
+		Config string   `argum:"-c,--config" placeholder:"FILE"`
+		Hosts  []string `argum:"--hosts" placeholder:"HOST"`
+		Files  []string `argum:"pos" placeholder:"PATH"`
+	}
+
+	uf, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := bytes.NewBuffer([]byte{})
+	uf.writeUsage(w)
+	uf.writeHelp(w)
+	t.Log(w.String())
+
+	for _, s := range []string{"[-c=FILE]", "[--hosts=HOST...]", "[PATH...]", "-c, --config=FILE"} {
+		if !strings.Contains(w.String(), s) {
+			t.Errorf("output should contain '%s'", s)
+		}
+	}
+}
+
+type pingCmd struct {
+	IP string `argum:"req,pos"`
+}
+
+func (pingCmd) Examples() []string {
+	return []string{"prog ping 10.0.0.1"}
+}
+
+type epilogArgs struct {
+	Ping *pingCmd
+}
+
+func (epilogArgs) Examples() []string {
+	return []string{"prog -h", "prog ping 127.0.0.1"}
+}
+
+func (epilogArgs) Epilog() string {
+	return "documentation: https://example.com/docs"
+}
+
+func TestExamplesEpilog(t *testing.T) {
+	var args epilogArgs
+	uf, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := bytes.NewBuffer([]byte{})
+	uf.writeUsageHelp(w)
+	t.Log(w.String())
+
+	for _, s := range []string{"\nexamples:\n  prog -h\n  prog ping 127.0.0.1\n", "    examples:\n      prog ping 10.0.0.1\n", "\ndocumentation: https://example.com/docs\n"} {
+		if !strings.Contains(w.String(), s) {
+			t.Errorf("help should contain '%s'", s)
+		}
+	}
+}
@@ -0,0 +1,547 @@
+package argum
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator is implemented by structures that check parsed arguments, e.g. by cross-field rules.
+// Validate is called after parsing on root structure and on each selected nested command, nested first
+type Validator interface {
+	Validate() error
+}
+
+// validate call Validate method of selected nested structures and then of structure itself,
+// errors of nested commands are prefixed with command name
+func (s *structure) validate(parents ...*structure) error {
+	if err := s.openDefaultFiles(); err != nil {
+		return err
+	}
+
+	// counters are incremented by each occurrence, so their range is checked after parsing
+	for _, f := range s.fields {
+		if f.count && f.taken {
+			if err := f.checkValue(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range s.fields {
+		if f.s == nil || !f.s.taken {
+			continue
+		}
+
+		if err := f.s.validate(append(parents, s)...); err != nil {
+			if f.oneof || f.emb {
+				return err
+			}
+			return fmt.Errorf("%s: %s", f.name, err)
+		}
+	}
+
+	// groups and dependencies may include fields of embedded structures, so they are checked by parent structure
+	if !s.emb {
+		if err := s.checkGroups(); err != nil {
+			return err
+		}
+		if err := s.checkDependencies(parents); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := s.i.(Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+// openDefaultFiles open default files of arguments which are not given
+func (s *structure) openDefaultFiles() error {
+	for _, f := range s.flatFields() {
+		if !f.fileDef || f.taken {
+			continue
+		}
+		f.fileDef = false
+		if err := f.setDefault(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatFields return fields of structure together with fields of embedded structures
+func (s *structure) flatFields() (fields []*field) {
+	for _, f := range s.fields {
+		if f.emb {
+			fields = append(fields, f.s.flatFields()...)
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	return
+}
+
+// checkGroups check that only one argument of each mutually exclusive group is set,
+// and that one is set if group is required
+func (s *structure) checkGroups() error {
+	var groups []string
+	members := make(map[string][]*field)
+	for _, f := range s.flatFields() {
+		if f.xor == "" {
+			continue
+		}
+		if _, ok := members[f.xor]; !ok {
+			groups = append(groups, f.xor)
+		}
+		members[f.xor] = append(members[f.xor], f)
+	}
+
+	for _, group := range groups {
+		var taken, names []string
+		var req bool
+		for _, f := range members[group] {
+			if f.taken {
+				taken = append(taken, f.displayName())
+			}
+			names = append(names, f.displayName())
+			req = req || f.xorreq
+		}
+
+		if len(taken) > 1 {
+			return fmt.Errorf("%s and %s cannot be used together", taken[0], taken[1])
+		}
+		if req && len(taken) == 0 {
+			return fmt.Errorf("one of %s is required", strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
+
+// checkDependencies check requires, conflicts, required_if and required_unless tags of fields,
+// referenced arguments are looked up in structure and then in its parents
+func (s *structure) checkDependencies(parents []*structure) error {
+	for _, f := range s.flatFields() {
+		for _, key := range f.requires {
+			ref, err := s.lookupReference(parents, f, key)
+			if err != nil {
+				return err
+			}
+			if f.taken && !ref.taken {
+				return fmt.Errorf("%s requires %s", f.displayName(), key)
+			}
+		}
+
+		for _, key := range f.conflicts {
+			ref, err := s.lookupReference(parents, f, key)
+			if err != nil {
+				return err
+			}
+			if f.taken && ref.taken {
+				return fmt.Errorf("%s conflicts with %s", f.displayName(), key)
+			}
+		}
+
+		if f.taken {
+			continue
+		}
+
+		for _, cond := range f.requiredIf {
+			ok, err := s.matchCondition(parents, f, cond)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return fmt.Errorf("%s is required when %s", f.displayName(), cond)
+			}
+		}
+
+		for _, cond := range f.requiredUnless {
+			ok, err := s.matchCondition(parents, f, cond)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s is required unless %s", f.displayName(), cond)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkReferences check that arguments referenced by dependency tags exist in structure or its parents
+func (s *structure) checkReferences(parents ...*structure) error {
+	for _, f := range s.fields {
+		if f.s == nil {
+			continue
+		}
+		if err := f.s.checkReferences(append(parents, s)...); err != nil {
+			return err
+		}
+	}
+
+	// references of embedded structure fields are checked by parent structure
+	if s.emb {
+		return nil
+	}
+
+	for _, f := range s.flatFields() {
+		var keys []string
+		keys = append(keys, f.requires...)
+		keys = append(keys, f.conflicts...)
+		for _, cond := range append(append([]string{}, f.requiredIf...), f.requiredUnless...) {
+			key, _, _ := strings.Cut(cond, "=")
+			keys = append(keys, key)
+		}
+
+		for _, key := range keys {
+			if _, err := s.lookupReference(parents, f, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchCondition check condition like --mode=tls by value of referenced argument, or condition like --token by that argument is set
+func (s *structure) matchCondition(parents []*structure, f *field, cond string) (bool, error) {
+	key, val, withValue := strings.Cut(cond, "=")
+
+	ref, err := s.lookupReference(parents, f, key)
+	if err != nil {
+		return false, err
+	}
+
+	if !withValue {
+		return ref.taken, nil
+	}
+
+	for _, v := range elemValues(ref.v) {
+		if fmt.Sprint(v.Interface()) == val {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// lookupReference search argument by key or name in structure and its parents, nearest first
+func (s *structure) lookupReference(parents []*structure, f *field, key string) (*field, error) {
+	structs := []*structure{s}
+	for i := len(parents) - 1; i >= 0; i-- {
+		structs = append(structs, parents[i])
+	}
+
+	for _, st := range structs {
+		for _, ref := range st.flatFields() {
+			if ref.long == key || ref.short == key || ref.name == key {
+				return ref, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("argument '%s' references unknown argument %s", f.name, key)
+}
+
+// prepareChecks read validation tags of field: path, ext, dependencies, min, max, minlen, maxlen and pattern
+func (f *field) prepareChecks(sf reflect.StructField) error {
+	if path := sf.Tag.Get("path"); path != "" {
+		for _, c := range strings.Split(path, ",") {
+			switch c {
+			case "exists", "file", "dir", "absent":
+				f.path = append(f.path, c)
+			default:
+				return fmt.Errorf("argument '%s' have unexpected path constraint: %s", f.name, c)
+			}
+		}
+	}
+
+	if ext := sf.Tag.Get("ext"); ext != "" {
+		f.exts = strings.Split(ext, "|")
+	}
+
+	for key, p := range map[string]*[]string{"requires": &f.requires, "conflicts": &f.conflicts, "required_if": &f.requiredIf, "required_unless": &f.requiredUnless} {
+		if tag := sf.Tag.Get(key); tag != "" {
+			*p = strings.Split(tag, ",")
+		}
+	}
+
+	if (len(f.path) > 0 || len(f.exts) > 0) && f.v.Type() != reflect.TypeOf("") && f.v.Type() != reflect.TypeOf([]string{}) {
+		return fmt.Errorf("invalid `%s`, path constraints are applicable only to string and []string", f.name)
+	}
+
+	if err := f.prepareRange(sf); err != nil {
+		return err
+	}
+
+	for key, p := range map[string]*int{"minlen": &f.minlen, "maxlen": &f.maxlen} {
+		if tag := sf.Tag.Get(key); tag != "" {
+			n, err := strconv.Atoi(tag)
+			if err != nil || n < 0 {
+				return fmt.Errorf("argument '%s' have invalid %s: %s", f.name, key, tag)
+			}
+			*p = n
+		}
+	}
+
+	if pattern := sf.Tag.Get("pattern"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("argument '%s' have invalid pattern: %s", f.name, err)
+		}
+		f.pattern = re
+	}
+
+	isString := elemType(f.v.Type()).Kind() == reflect.String
+	isSlice := f.v.Kind() == reflect.Slice && !isValueType(f.v.Type())
+
+	if (f.minlen > 0 || f.maxlen > 0) && !isString && !isSlice {
+		return fmt.Errorf("invalid `%s`, minlen and maxlen are applicable only to strings and slices", f.name)
+	}
+
+	if f.pattern != nil && !isString {
+		return fmt.Errorf("invalid `%s`, pattern is applicable only to strings", f.name)
+	}
+
+	return nil
+}
+
+// prepareRange parse min and max tags to values of field element type
+func (f *field) prepareRange(sf reflect.StructField) (err error) {
+	min, max := sf.Tag.Get("min"), sf.Tag.Get("max")
+	if min == "" && max == "" {
+		return nil
+	}
+
+	t := elemType(f.v.Type())
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return fmt.Errorf("invalid `%s`, min and max are applicable only to numbers and durations", f.name)
+	}
+
+	if min != "" {
+		if f.min, err = f.parseValue(t, min); err != nil {
+			return fmt.Errorf("argument '%s' have invalid min: %s", f.name, err)
+		}
+	}
+	if max != "" {
+		if f.max, err = f.parseValue(t, max); err != nil {
+			return fmt.Errorf("argument '%s' have invalid max: %s", f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// elemType return type of single value of field: type of slice element, pointed type or type of Optional value
+func elemType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice && !isValueType(t) {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if elem, ok := optionalElem(t); ok {
+		t = elem
+	}
+	return t
+}
+
+// elemValues return single values of field: elements of slice, pointed value or value of Optional
+func elemValues(v reflect.Value) (vals []reflect.Value) {
+	if v.Kind() == reflect.Slice && !isValueType(v.Type()) {
+		for i := 0; i < v.Len(); i++ {
+			vals = append(vals, elemValues(v.Index(i))...)
+		}
+		return
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if _, ok := optionalElem(v.Type()); ok {
+		v = v.MethodByName("Get").Call(nil)[0]
+	}
+
+	return []reflect.Value{v}
+}
+
+// checkValue validate value of field by constraint tags
+func (f *field) checkValue() error {
+	if f.v.Kind() == reflect.Slice && !isValueType(f.v.Type()) {
+		if err := f.checkLen(f.v.Len()); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range elemValues(f.v) {
+		if err := f.checkRange(v); err != nil {
+			return err
+		}
+
+		if v.Kind() != reflect.String {
+			continue
+		}
+
+		if f.v.Kind() != reflect.Slice {
+			if err := f.checkLen(utf8.RuneCountInString(v.String())); err != nil {
+				return err
+			}
+		}
+
+		if f.pattern != nil && !f.pattern.MatchString(v.String()) {
+			return fmt.Errorf("value %s for '%s' does not match pattern %s", v.String(), f.name, f.pattern)
+		}
+
+		if err := f.checkPath(v.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *field) checkRange(v reflect.Value) error {
+	if f.min.IsValid() && compareValues(v, f.min) < 0 || f.max.IsValid() && compareValues(v, f.max) > 0 {
+		return fmt.Errorf("value %v for '%s' is out of range %s", v.Interface(), f.name, f.rangeString())
+	}
+	return nil
+}
+
+func (f *field) checkLen(n int) error {
+	if f.minlen > 0 && n < f.minlen || f.maxlen > 0 && n > f.maxlen {
+		return fmt.Errorf("length of '%s' should be in range %s", f.name, f.lenString())
+	}
+	return nil
+}
+
+// compareValues compare numbers of same kind, returns -1, 0 or 1
+func compareValues(a, b reflect.Value) int {
+	var less, greater bool
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	case reflect.Float32, reflect.Float64:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	}
+
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func (f *field) rangeString() string {
+	var min, max string
+	if f.min.IsValid() {
+		min = fmt.Sprint(f.min.Interface())
+	}
+	if f.max.IsValid() {
+		max = fmt.Sprint(f.max.Interface())
+	}
+	return "[" + min + ".." + max + "]"
+}
+
+func (f *field) lenString() string {
+	var min, max string
+	if f.minlen > 0 {
+		min = strconv.Itoa(f.minlen)
+	}
+	if f.maxlen > 0 {
+		max = strconv.Itoa(f.maxlen)
+	}
+	return "[" + min + ".." + max + "]"
+}
+
+func (f *field) checkPath(path string) error {
+	if len(f.exts) > 0 && !contains(f.exts, filepath.Ext(path)) {
+		return fmt.Errorf("file %s for '%s' should have extension %s", path, f.name, strings.Join(f.exts, "|"))
+	}
+
+	for _, c := range f.path {
+		info, err := os.Stat(path)
+		switch {
+		case c == "absent" && err == nil:
+			return fmt.Errorf("path %s for '%s' already exists", path, f.name)
+		case c == "absent":
+		case err != nil:
+			return fmt.Errorf("path %s for '%s' does not exist", path, f.name)
+		case c == "file" && info.IsDir():
+			return fmt.Errorf("path %s for '%s' is not a file", path, f.name)
+		case c == "dir" && !info.IsDir():
+			return fmt.Errorf("path %s for '%s' is not a directory", path, f.name)
+		}
+	}
+
+	return nil
+}
+
+// constraints return descriptions of field constraints for output to help
+func (f *field) constraints() (cs []string) {
+	for _, c := range f.path {
+		switch c {
+		case "exists":
+			cs = append(cs, "existing path")
+		case "file":
+			cs = append(cs, "existing file")
+		case "dir":
+			cs = append(cs, "existing directory")
+		case "absent":
+			cs = append(cs, "path must not exist")
+		}
+	}
+
+	if len(f.exts) > 0 {
+		cs = append(cs, strings.Join(f.exts, "|"))
+	}
+
+	if f.min.IsValid() || f.max.IsValid() {
+		cs = append(cs, strings.Trim(f.rangeString(), "[]"))
+	}
+
+	if f.minlen > 0 || f.maxlen > 0 {
+		cs = append(cs, "length "+strings.Trim(f.lenString(), "[]"))
+	}
+
+	if f.pattern != nil {
+		cs = append(cs, "pattern "+f.pattern.String())
+	}
+
+	for _, key := range f.requires {
+		cs = append(cs, "requires "+key)
+	}
+	for _, key := range f.conflicts {
+		cs = append(cs, "conflicts with "+key)
+	}
+	for _, cond := range f.requiredIf {
+		cs = append(cs, "required if "+cond)
+	}
+	for _, cond := range f.requiredUnless {
+		cs = append(cs, "required unless "+cond)
+	}
+
+	return
+}
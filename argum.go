@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
@@ -12,8 +13,36 @@ var (
 	Description string
 	// Version is global variable contains version of main application
 	Version string
-	name    string
-	s       *structure
+	// Now returns current time, it used to resolve relative time values and may be replaced in tests
+	Now  = time.Now
+	name string
+	s    *structure
+)
+
+// DuplicatePolicy defines how scalar argument given several times is handled
+type DuplicatePolicy int
+
+const (
+	// DuplicateError return error if argument is given twice
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateFirst keep value of first occurrence
+	DuplicateFirst
+	// DuplicateLast keep value of last occurrence
+	DuplicateLast
+)
+
+// Duplicates is policy for scalar arguments given several times, it can be overridden for field by tag `duplicate:"error|first|last"`
+var Duplicates = DuplicateError
+
+var (
+	// AllowAbbrev allow to type unambiguous prefix of long option, e.g. `--verb` for `--verbose`
+	AllowAbbrev bool
+	// IgnoreCase match long options, command names and variants case-insensitively, short options stay case-sensitive
+	IgnoreCase bool
+	// POSIXMode stop option parsing at first operand, as POSIXLY_CORRECT does, rest arguments are set to positionals
+	POSIXMode bool
+	// ResponseFiles expand `@file` arguments to arguments read from file, one per line or shell-style quoted
+	ResponseFiles bool
 )
 
 // MustParse parse os.Args for struct and fatal if it has error
@@ -26,7 +55,15 @@ func MustParse(i interface{}) {
 
 // Parse os.Args for incomimng struct and return error
 func Parse(i interface{}) error {
-	if Version != "" && contains(os.Args, "--version") {
+	args := os.Args[1:]
+	if ResponseFiles {
+		var err error
+		if args, err = expandResponseFiles(args); err != nil {
+			return err
+		}
+	}
+
+	if Version != "" && contains(options(args), "--version") {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
@@ -42,7 +79,7 @@ func Parse(i interface{}) error {
 		return fmt.Errorf("failed prepare structure, %s", err)
 	}
 
-	if contains(os.Args[1:], "--help", "-h") {
+	if contains(options(args), "--help", "-h") {
 		// INFO: temporary hidden help for specify command, as now output all help information
 		// for _, f := range s.fields {
 		// 	if f.command && contains(os.Args[1:], f.name) {
@@ -55,8 +92,7 @@ func Parse(i interface{}) error {
 		os.Exit(0)
 	}
 
-	_, err = s.parseArgs(os.Args[1:])
-	return err
+	return s.parse(args)
 }
 
 // PrintHelp to stdout end exit
@@ -89,10 +125,14 @@ func splitArg(s string) (string, []string) {
 }
 
 func splitValues(s string) (vals []string) {
-	if matchEscape(s) {
+	return splitValuesSep(s, ",")
+}
+
+func splitValuesSep(s, sep string) (vals []string) {
+	if matchEscape(s) || sep == "" {
 		vals = []string{s}
 	} else {
-		vals = strings.Split(s, ",")
+		vals = strings.Split(s, sep)
 	}
 	return vals
 }
@@ -113,6 +153,16 @@ func trim(s string) string {
 	return s
 }
 
+// options return arguments before `--` terminator
+func options(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i]
+		}
+	}
+	return args
+}
+
 func contains(strslice []string, ss ...string) bool {
 	for _, str := range strslice {
 		for _, s := range ss {
@@ -124,6 +174,22 @@ func contains(strslice []string, ss ...string) bool {
 	return false
 }
 
+// equalName compare names considering IgnoreCase policy
+func equalName(a, b string) bool {
+	if IgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// hasNamePrefix report whether name begins with prefix considering IgnoreCase policy
+func hasNamePrefix(name, prefix string) bool {
+	if IgnoreCase {
+		return strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))
+	}
+	return strings.HasPrefix(name, prefix)
+}
+
 func matchShort(s string) bool {
 	return len(s) > 1 && s[0] == '-' && s[1] != '-'
 }
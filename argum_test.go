@@ -1,9 +1,15 @@
 package argum
 
 import (
+	"fmt"
 	"log"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -485,6 +491,1249 @@ func TestChoose(t *testing.T) {
 	}
 }
 
+func TestNegatable(t *testing.T) {
+	var args struct {
+		Cache  bool `argum:"--cache,negatable" default:"true"`
+		Color  bool `argum:"-c,--color,negatable"`
+		Strict bool `argum:"negatable"`
+	}
+
+	err = prepAndParse(&args, []string{"--no-cache", "--color", "--no-strict"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.Cache, false, "failed negate boolean with default value")
+	check(t, args.Color, true, "failed set negatable boolean")
+	check(t, args.Strict, false, "failed negate boolean with auto long key")
+
+	err = prepAndParse(&args, []string{"--no-cache=true"})
+	if err == nil {
+		t.Error("should be error, as negative form does not take a value")
+	}
+
+	var invalid struct {
+		N int `argum:"--n,negatable"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as only boolean argument can be negatable")
+	}
+}
+
+func TestCounter(t *testing.T) {
+	var args struct {
+		Verbose int  `argum:"-v,--verbose,count"`
+		Quiet   bool `argum:"-q"`
+		Name    string
+	}
+
+	err = prepAndParse(&args, []string{"-v", "-vv", "--name", "str", "-v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Verbose, 4, "failed count repeated arguments")
+	check(t, args.Name, "str", "failed set value after counter")
+
+	err = prepAndParse(&args, []string{"-qvv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Verbose, 2, "failed count arguments joined with boolean")
+	check(t, args.Quiet, true, "failed set boolean joined with counter")
+
+	err = prepAndParse(&args, []string{"--verbose=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Verbose, 3, "failed set counter directly")
+
+	var limited struct {
+		V int `argum:"-v,count" min:"2" max:"3"`
+	}
+	err = prepAndParse(&limited, []string{"-v", "-vv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, limited.V, 3, "failed count arguments within range")
+
+	if err := prepAndParse(&limited, []string{"-vvvv"}); err == nil {
+		t.Error("should be error, as counter is out of range")
+	}
+	if err := prepAndParse(&limited, []string{"-v"}); err == nil {
+		t.Error("should be error, as counter is less than min")
+	}
+
+	var invalid struct {
+		V string `argum:"-v,count"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as only integer argument can be counter")
+	}
+}
+
+func TestIntegers(t *testing.T) {
+	var args struct {
+		I8   int8
+		I32  int32
+		I64  int64
+		U    uint
+		U16  uint16
+		U64  uint64
+		Uu32 []uint32
+		Ii8  []int8
+	}
+
+	err = prepAndParse(&args, []string{"--i8=-128", "--i32", "0x7fff_ffff", "--i64=1_000_000", "-u=0b101", "--u16", "0o17", "--u64", "010", "--uu32=1,0x10", "--ii8=-1,-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.I8, int8(-128), "failed set int8")
+	check(t, args.I32, int32(0x7fffffff), "failed set int32 with hex prefix")
+	check(t, args.I64, int64(1000000), "failed set int64 with separators")
+	check(t, args.U, uint(5), "failed set uint with binary prefix")
+	check(t, args.U16, uint16(15), "failed set uint16 with octal prefix")
+	check(t, args.U64, uint64(10), "failed set uint64 with leading zero")
+	check(t, len(args.Uu32), 2, "failed set slice of uint32")
+	check(t, len(args.Ii8), 2, "failed set slice of int8")
+
+	for _, osargs := range [][]string{{"--i8=128"}, {"--u16=-1"}, {"-u=abc"}, {"--uu32=4294967296"}} {
+		if err := prepAndParse(&args, osargs); err == nil {
+			t.Errorf("should be error for %s", osargs)
+		}
+	}
+}
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	case "error":
+		*l = 3
+	default:
+		return fmt.Errorf("unknown log level %s", text)
+	}
+	return nil
+}
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"", "debug", "info", "error"}[l]), nil
+}
+
+type region struct {
+	Name string
+}
+
+func (r *region) UnmarshalText(text []byte) error {
+	r.Name = string(text)
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	var args struct {
+		Level   logLevel `default:"info"`
+		Levels  []logLevel
+		Region  region  `argum:"pos"`
+		PRegion *region `argum:"--pregion"`
+		Regions []*region
+	}
+
+	err = prepAndParse(&args, []string{"eu-west", "--levels=debug,error", "--pregion", "us-east", "--regions", "a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.Level, logLevel(2), "failed set default value to text unmarshaler")
+	check(t, len(args.Levels), 2, "failed set slice of text unmarshalers")
+	check(t, args.Region.Name, "eu-west", "failed set positional text unmarshaler struct")
+	if args.PRegion == nil || args.PRegion.Name != "us-east" {
+		t.Error("failed set pointer to text unmarshaler")
+	}
+	check(t, len(args.Regions), 2, "failed set slice of pointers to text unmarshaler")
+
+	err = prepAndParse(&args, []string{"--level", "trace"})
+	if err == nil {
+		t.Error("should be error, as unmarshaler returns error")
+	}
+
+	var preset struct {
+		Level logLevel
+	}
+	preset.Level = 3
+	s, err := prepareStructure(&preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[0].defaultValue(), "error", "failed render default value by text marshaler")
+}
+
+type listValue []string
+
+func (l *listValue) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func (l *listValue) String() string {
+	return strings.Join(*l, ";")
+}
+
+func (l *listValue) Type() string {
+	return "item"
+}
+
+type pairValue struct {
+	Key, Val string
+}
+
+func (p *pairValue) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("expected key=value")
+	}
+	p.Key, p.Val = kv[0], kv[1]
+	return nil
+}
+
+func (p *pairValue) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.Key + "=" + p.Val
+}
+
+func TestValueInterface(t *testing.T) {
+	var args struct {
+		List listValue  `argum:"-l" default:"first"`
+		Pair *pairValue `argum:"--pair"`
+		Pos  listValue  `argum:"pos"`
+	}
+
+	err = prepAndParse(&args, []string{"-l", "a,b", "--pair", "k=v,w", "-l=c", "pos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.List.String(), "first;a,b;c", "failed accumulate raw values")
+	if args.Pair == nil || args.Pair.Key != "k" || args.Pair.Val != "v,w" {
+		t.Error("failed set pointer value")
+	}
+	check(t, args.Pos.String(), "pos", "failed set positional value")
+
+	err = prepAndParse(&args, []string{"--pair", "novalue"})
+	if err == nil {
+		t.Error("should be error, as Set returns error")
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[0].valueType(), "<item>", "failed get placeholder from Type method")
+	check(t, s.fields[1].valueType(), "<value>", "failed get default placeholder")
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType(func(s string) (point, error) {
+		var p point
+		_, err := fmt.Sscanf(s, "%d:%d", &p.X, &p.Y)
+		return p, err
+	}, "x:y")
+
+	var args struct {
+		Point  point `default:"1:2"`
+		PPoint *point
+		Points []point
+	}
+
+	err = prepAndParse(&args, []string{"--ppoint", "3:4", "--points=5:6,7:8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.Point, point{1, 2}, "failed set default value of registered type")
+	if args.PPoint == nil || *args.PPoint != (point{3, 4}) {
+		t.Error("failed set pointer to registered type")
+	}
+	check(t, len(args.Points), 2, "failed set slice of registered type")
+
+	err = prepAndParse(&args, []string{"--ppoint", "3"})
+	if err == nil {
+		t.Error("should be error, as value is invalid")
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[0].valueType(), "<x:y>", "failed get placeholder of registered type")
+	check(t, s.fields[2].valueType(), "[x:y...]", "failed get placeholder of slice of registered type")
+}
+
+func TestMap(t *testing.T) {
+	var args struct {
+		Labels map[string]string `argum:"-l,--label"`
+		Limits map[string]int    `default:"cpu=1,mem=2"`
+		Pos    string            `argum:"pos"`
+	}
+
+	err = prepAndParse(&args, []string{"--label", "a=1", "b=2", "pos", "-l=c=3,d=4", "--limits", "cpu=4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, len(args.Labels), 4, "failed accumulate map entries")
+	check(t, args.Labels["c"], "3", "failed set map entry")
+	check(t, args.Pos, "pos", "failed set positional after map entries")
+	check(t, len(args.Limits), 1, "failed replace default map")
+	check(t, args.Limits["cpu"], 4, "failed set map entry of integer")
+
+	err = prepAndParse(&args, []string{"--limits", "cpu=x"})
+	if err == nil || !strings.Contains(err.Error(), "cpu") {
+		t.Errorf("should be error with name of bad key, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"--label", "novalue"})
+	if err == nil {
+		t.Error("should be error, as value is not key=value pair")
+	}
+
+	err = prepAndParse(&args, []string{"--label=k=a,b"})
+	if err == nil || err.Error() != "invalid value b for 'labels', expected key=value" {
+		t.Errorf("should be error with element of value which is not pair, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"--label", "k=a,b"})
+	if err == nil || err.Error() != "invalid value b for 'labels', expected key=value" {
+		t.Errorf("should be error with element of value which is not pair, %v", err)
+	}
+
+	var separgs struct {
+		Labels map[string]string `argum:"--label" sep:";"`
+	}
+	err = prepAndParse(&separgs, []string{"--label=k=a,b;x=y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, separgs.Labels["k"], "a,b", "failed keep value containing comma with other separator")
+	check(t, separgs.Labels["x"], "y", "failed split map entries by separator")
+}
+
+func TestOptional(t *testing.T) {
+	var args struct {
+		Port    *int
+		Name    *string `default:"name"`
+		Timeout *time.Duration
+		Debug   *bool `argum:"-d"`
+
+		Level Optional[int]    `default:"3"`
+		Host  Optional[string] `argum:"-o"`
+		Flag  Optional[bool]   `argum:"-f"`
+	}
+
+	err = prepAndParse(&args, []string{"--port", "0", "-o", "localhost", "-f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args.Port == nil || *args.Port != 0 {
+		t.Error("failed set pointer to integer")
+	}
+	if args.Name == nil || *args.Name != "name" {
+		t.Error("failed set default value to pointer")
+	}
+	if args.Timeout != nil || args.Debug != nil {
+		t.Error("pointer should be nil if value not set")
+	}
+
+	check(t, args.Level.IsSet(), false, "default value should not be considered as set")
+	check(t, args.Level.Get(), 3, "failed set default value to optional")
+	check(t, args.Host.IsSet(), true, "failed set optional value")
+	check(t, args.Host.Get(), "localhost", "failed set optional string")
+	check(t, args.Flag.Get(), true, "failed set optional boolean")
+
+	err = prepAndParse(&args, []string{"-d", "--timeout", "1s", "--level", "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Debug == nil || !*args.Debug {
+		t.Error("failed set pointer to boolean")
+	}
+	if args.Timeout == nil || *args.Timeout != time.Second {
+		t.Error("failed set pointer to duration")
+	}
+	check(t, args.Level.IsSet(), true, "failed set optional to zero value")
+	check(t, args.Level.Get(), 0, "failed set optional to zero value")
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[0].valueType(), "<n>", "failed get placeholder of pointer")
+	check(t, s.fields[4].valueType(), "<n>", "failed get placeholder of optional")
+}
+
+func TestTime(t *testing.T) {
+	Now = func() time.Time {
+		return time.Date(2020, 5, 10, 15, 30, 0, 0, time.UTC)
+	}
+	defer func() { Now = time.Now }()
+
+	var args struct {
+		Since time.Time   `layout:"2006-01-02"`
+		Until time.Time   `default:"now"`
+		From  *time.Time  `argum:"--from"`
+		Days  []time.Time `layout:"2006-01-02"`
+		Pos   time.Time   `argum:"pos"`
+	}
+
+	err = prepAndParse(&args, []string{"--since", "2020-01-02", "--from=-2h", "--days=today,yesterday", "2020-05-01T10:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.Since, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), "failed set time by layout")
+	check(t, args.Until, Now(), "failed set default relative time")
+	if args.From == nil || !args.From.Equal(time.Date(2020, 5, 10, 13, 30, 0, 0, time.UTC)) {
+		t.Error("failed set relative time to pointer")
+	}
+	if len(args.Days) != 2 || !args.Days[1].Equal(time.Date(2020, 5, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Error("failed set slice of time")
+	}
+	check(t, args.Pos, time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC), "failed set positional time by RFC3339")
+
+	err = prepAndParse(&args, []string{"--until", "-2h", "--from", "-1d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Until, time.Date(2020, 5, 10, 13, 30, 0, 0, time.UTC), "failed set relative time by separate value")
+	if args.From == nil || !args.From.Equal(time.Date(2020, 5, 9, 15, 30, 0, 0, time.UTC)) {
+		t.Error("failed set relative time to pointer by separate value")
+	}
+
+	err = prepAndParse(&args, []string{"--since", "01.02.2020"})
+	if err == nil {
+		t.Error("should be error, as time does not match layout")
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[0].valueType(), "<2006-01-02>", "failed show layout as placeholder")
+	check(t, s.fields[3].valueType(), "[2006-01-02...]", "failed show layout as placeholder of slice")
+}
+
+func TestNetworkTypes(t *testing.T) {
+	var args struct {
+		Addr   netip.Addr
+		Listen netip.AddrPort `default:"127.0.0.1:8080"`
+		Prefix netip.Prefix
+		IP     net.IP
+		IPs    []net.IP
+		Net    net.IPNet
+		MAC    net.HardwareAddr
+		URL    *url.URL `scheme:"http|https"`
+		Addrs  []netip.Addr
+	}
+
+	err = prepAndParse(&args, []string{"--addr", "::1", "--prefix", "10.0.0.0/8", "--ip", "10.0.0.1", "--ips=10.0.0.2,10.0.0.3", "--net", "192.168.0.0/16", "--mac", "00:00:5e:00:53:01", "--url", "https://example.com/path", "--addrs", "1.1.1.1", "8.8.8.8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, args.Addr, netip.MustParseAddr("::1"), "failed set netip.Addr")
+	check(t, args.Listen, netip.MustParseAddrPort("127.0.0.1:8080"), "failed set default netip.AddrPort")
+	check(t, args.Prefix, netip.MustParsePrefix("10.0.0.0/8"), "failed set netip.Prefix")
+	check(t, args.IP.String(), "10.0.0.1", "failed set net.IP")
+	check(t, len(args.IPs), 2, "failed set slice of net.IP")
+	check(t, args.Net.String(), "192.168.0.0/16", "failed set net.IPNet")
+	check(t, args.MAC.String(), "00:00:5e:00:53:01", "failed set net.HardwareAddr")
+	if args.URL == nil || args.URL.Host != "example.com" {
+		t.Error("failed set url")
+	}
+	check(t, len(args.Addrs), 2, "failed set slice of netip.Addr")
+
+	for _, osargs := range [][]string{{"--addr", "256.0.0.1"}, {"--listen", "localhost"}, {"--ip", "x"}, {"--url", "ftp://example.com"}, {"--mac", "00:00"}} {
+		if err := prepAndParse(&args, osargs); err == nil {
+			t.Errorf("should be error for %s", osargs)
+		}
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, s.fields[1].valueType(), "<ip:port>", "failed get placeholder of netip.AddrPort")
+	check(t, s.fields[4].valueType(), "[ip...]", "failed get placeholder of slice of net.IP")
+	check(t, s.fields[7].valueType(), "<url>", "failed get placeholder of url")
+}
+
+func TestByteSize(t *testing.T) {
+	for s, size := range map[string]ByteSize{"0": 0, "512": 512, "10k": 10000, "1kB": 1000, "1KiB": 1024, "512MiB": 512 << 20, "1.5GB": 15e8, "2 gib": 2 << 30, "1EiB": 1 << 60} {
+		b, err := ParseByteSize(s)
+		if err != nil {
+			t.Error(err)
+		}
+		check(t, b, size, "failed parse byte size "+s)
+	}
+
+	for _, s := range []string{"", "MB", "10XB", "-1MB", "16EiB"} {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Errorf("should be error for %s", s)
+		}
+	}
+
+	check(t, ByteSize(512<<20).String(), "512MiB", "failed format byte size")
+	check(t, ByteSize(2e9).String(), "2GB", "failed format byte size")
+	check(t, ByteSize(1001).String(), "1001B", "failed format byte size")
+
+	var args struct {
+		Max   ByteSize   `default:"1MiB"`
+		Sizes []ByteSize `argum:"--sizes"`
+	}
+	err = prepAndParse(&args, []string{"--sizes", "1k,2KiB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Max, ByteSize(1<<20), "failed set default byte size")
+	check(t, len(args.Sizes), 2, "failed set slice of byte sizes")
+}
+
+func TestExtendedDuration(t *testing.T) {
+	for s, d := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "2w": 14 * 24 * time.Hour, "1w2d3h": 9*24*time.Hour + 3*time.Hour, "1.5d": 36 * time.Hour, "-1d12h": -36 * time.Hour, "90m": 90 * time.Minute} {
+		x, err := ParseDuration(s)
+		if err != nil {
+			t.Error(err)
+		}
+		check(t, time.Duration(x), d, "failed parse duration "+s)
+	}
+
+	for _, s := range []string{"", "-", "d", "1x", "3h2d"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("should be error for %s", s)
+		}
+	}
+
+	check(t, Duration(9*24*time.Hour+3*time.Hour).String(), "1w2d3h", "failed format duration")
+	check(t, Duration(-36*time.Hour).String(), "-1d12h", "failed format negative duration")
+	check(t, Duration(90*time.Second).String(), "1m30s", "failed format duration without days")
+	check(t, Duration(0).String(), "0s", "failed format zero duration")
+
+	var args struct {
+		Retention Duration   `default:"4w"`
+		Intervals []Duration `argum:"--intervals"`
+	}
+	err = prepAndParse(&args, []string{"--intervals=1d,12h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Retention, Duration(28*24*time.Hour), "failed set default duration")
+	check(t, len(args.Intervals), 2, "failed set slice of durations")
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte("input"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var args struct {
+		Input  InputFile   `argum:"-i" default:"-"`
+		Output OutputFile  `argum:"-o"`
+		Files  []InputFile `argum:"pos"`
+	}
+
+	err = prepAndParse(&args, []string{"-o", filepath.Join(dir, "out.txt"), in, in})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args.Input.File != os.Stdin {
+		t.Error("failed set stdin by default value")
+	}
+	if _, err := args.Output.WriteString("output"); err != nil {
+		t.Error(err)
+	}
+	check(t, len(args.Files), 2, "failed open slice of files")
+	check(t, len(openedFiles), 3, "failed register opened files")
+
+	if err := CloseFiles(); err != nil {
+		t.Error(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "output" {
+		t.Error("failed write to output file")
+	}
+
+	err = prepAndParse(&args, []string{"-i", filepath.Join(dir, "notexists.txt")})
+	if err == nil || !strings.Contains(err.Error(), "'input'") {
+		t.Errorf("should be error attributed to field, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{in, filepath.Join(dir, "missing.txt")})
+	if err == nil || !strings.Contains(err.Error(), "'files'") {
+		t.Errorf("should be error attributed to field, as one of files is missing, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"-o", "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Output.File != os.Stdout {
+		t.Error("failed set stdout")
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := os.WriteFile("default.txt", []byte("precious"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var defargs struct {
+		Output OutputFile `argum:"-o" default:"default.txt"`
+	}
+
+	err = prepAndParse(&defargs, []string{"-o", "other.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("default.txt"); string(data) != "precious" {
+		t.Error("default file should not be created, as argument is given")
+	}
+
+	err = prepAndParse(&defargs, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defargs.Output.File == nil || defargs.Output.Name() != "default.txt" {
+		t.Error("failed open default file")
+	}
+	CloseFiles()
+
+	var lastargs struct {
+		Output OutputFile `argum:"-o" duplicate:"last"`
+		Port   int        `argum:"-p"`
+	}
+
+	os.WriteFile("default.txt", []byte("precious"), 0644)
+
+	err = prepAndParse(&lastargs, []string{"-o", "default.txt", "-p", "x"})
+	if err == nil {
+		t.Error("should be error, as port is not a number")
+	}
+	err = prepAndParse(&lastargs, []string{"-o", "default.txt", "-o", "last.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("default.txt"); string(data) != "precious" {
+		t.Error("output file should not be created, as parsing failed or it is overridden")
+	}
+	if lastargs.Output.File == nil || lastargs.Output.Name() != "last.txt" {
+		t.Error("failed create last output file")
+	}
+	check(t, len(openedFiles), 1, "failed register only created output file")
+	CloseFiles()
+}
+
+func TestPathConstraints(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("config"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var args struct {
+		Config string   `path:"file" ext:".yaml|.yml"`
+		Dir    string   `path:"dir"`
+		Out    string   `path:"absent"`
+		Files  []string `argum:"pos" path:"exists"`
+	}
+
+	err = prepAndParse(&args, []string{"--config", config, "--dir", dir, "--out", filepath.Join(dir, "out"), config, dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, osargs := range [][]string{
+		{"--config", dir},
+		{"--config", filepath.Join(dir, "notexists.yaml")},
+		{"--config", filepath.Join(dir, "config.json")},
+		{"--dir", config},
+		{"--out", config},
+		{config, filepath.Join(dir, "notexists")},
+	} {
+		if err := prepAndParse(&args, osargs); err == nil {
+			t.Errorf("should be error for %s", osargs)
+		}
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(s.fields[0].constraints(), ","), "existing file,.yaml|.yml", "failed describe constraints")
+
+	var invalid struct {
+		N int `path:"exists"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as path constraint is applicable only to strings")
+	}
+}
+
+func TestRangeConstraints(t *testing.T) {
+	var args struct {
+		Port    int           `min:"1" max:"65535"`
+		Ratio   float64       `min:"0" max:"1"`
+		Timeout time.Duration `min:"1s"`
+		Size    ByteSize      `max:"1GiB"`
+		Ports   []uint16      `max:"1024"`
+		Name    string        `minlen:"3" maxlen:"8" pattern:"^[a-z0-9-]+$"`
+		Tags    []string      `maxlen:"2" pattern:"^[a-z]+$"`
+		Level   *int          `min:"0" max:"5"`
+	}
+
+	err = prepAndParse(&args, []string{"--port", "8080", "--ratio", "0.5", "--timeout", "2s", "--size", "512MiB", "--ports=80,443", "--name", "my-app", "--tags=a,b", "--level", "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, osargs := range [][]string{
+		{"--port", "0"},
+		{"--port", "70000"},
+		{"--ratio", "1.5"},
+		{"--timeout", "500ms"},
+		{"--size", "2GiB"},
+		{"--ports=80,8080"},
+		{"--name", "ab"},
+		{"--name", "long-name-value"},
+		{"--name", "My_App"},
+		{"--tags=a,b,c"},
+		{"--tags=a,B"},
+		{"--level", "6"},
+	} {
+		if err := prepAndParse(&args, osargs); err == nil {
+			t.Errorf("should be error for %s", osargs)
+		}
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(s.fields[0].constraints(), ","), "1..65535", "failed describe range")
+	check(t, strings.Join(s.fields[2].constraints(), ","), "1s..", "failed describe duration range")
+	check(t, strings.Join(s.fields[5].constraints(), ","), "length 3..8,pattern ^[a-z0-9-]+$", "failed describe length and pattern")
+
+	var unicode struct {
+		Name string `maxlen:"3"`
+	}
+	err = prepAndParse(&unicode, []string{"--name", "ééé"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, unicode.Name, "ééé", "failed check length in characters")
+
+	var invalid struct {
+		S string `min:"1"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as min is applicable only to numbers")
+	}
+
+	var invalidLen struct {
+		N int `maxlen:"3"`
+	}
+	if _, err := prepareStructure(&invalidLen); err == nil {
+		t.Error("should be error, as maxlen is applicable only to strings and slices")
+	}
+
+	var invalidPattern struct {
+		N int `pattern:"^[0-9]+$"`
+	}
+	if _, err := prepareStructure(&invalidPattern); err == nil {
+		t.Error("should be error, as pattern is applicable only to strings")
+	}
+}
+
+var validated []string
+
+type tlsCmd struct {
+	Cert string
+	Key  string
+}
+
+func (c *tlsCmd) Validate() error {
+	validated = append(validated, "tls")
+	if c.Cert != "" && c.Key == "" {
+		return fmt.Errorf("--cert requires --key")
+	}
+	return nil
+}
+
+type plainCmd struct {
+	Port int
+}
+
+func (c *plainCmd) Validate() error {
+	validated = append(validated, "plain")
+	return nil
+}
+
+type validatedArgs struct {
+	TLS   *tlsCmd
+	Plain *plainCmd
+	From  int
+	To    int
+}
+
+func (a *validatedArgs) Validate() error {
+	validated = append(validated, "root")
+	if a.From > a.To {
+		return fmt.Errorf("--from should be before --to")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	var args validatedArgs
+
+	validated = nil
+	err = prepAndParse(&args, []string{"--from", "1", "--to", "2", "tls", "--cert", "c", "--key", "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(validated, ","), "tls,root", "failed call validate bottom-up only for selected commands")
+
+	err = prepAndParse(&args, []string{"--from", "2", "--to", "1"})
+	if err == nil {
+		t.Error("should be error of root validation")
+	}
+
+	err = prepAndParse(&args, []string{"tls", "--cert", "c"})
+	if err == nil || err.Error() != "tls: --cert requires --key" {
+		t.Errorf("should be error of command validation with command path, %v", err)
+	}
+}
+
+func TestXorGroups(t *testing.T) {
+	var args struct {
+		JSON  bool     `argum:"--json" xorreq:"format"`
+		YAML  bool     `argum:"--yaml" xor:"format"`
+		Table bool     `argum:"-t,--table" xor:"format"`
+		All   bool     `argum:"-a,--all" xor:"select"`
+		Names []string `argum:"pos" xor:"select"`
+	}
+
+	err = prepAndParse(&args, []string{"--json", "-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = prepAndParse(&args, []string{"--yaml", "first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = prepAndParse(&args, []string{"--json", "--yaml"})
+	if err == nil || err.Error() != "--json and --yaml cannot be used together" {
+		t.Errorf("should be error, as arguments are mutually exclusive, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"--table", "--all", "name"})
+	if err == nil {
+		t.Error("should be error, as positional excludes option")
+	}
+
+	err = prepAndParse(&args, []string{"-a"})
+	if err == nil {
+		t.Error("should be error, as one of group is required")
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := new(strings.Builder)
+	s.writeUsage(w)
+	if !strings.Contains(w.String(), " (--json | --yaml | --table) [--all | <names...>]\n") {
+		t.Errorf("failed output usage of groups: %s", w.String())
+	}
+}
+
+type serveCmd struct {
+	Cert string `requires:"--key"`
+	Key  string
+	CA   string `required_if:"--mode=tls"`
+}
+
+func TestDependencies(t *testing.T) {
+	var args struct {
+		Mode     string `default:"plain"`
+		Secure   bool   `conflicts:"--insecure"`
+		Insecure bool
+		Token    string
+		User     string `required_unless:"--token"`
+		Serve    *serveCmd
+	}
+
+	err = prepAndParse(&args, []string{"--token", "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = prepAndParse(&args, []string{"--user", "u", "--mode", "tls", "serve", "--cert", "c", "--key", "k", "--ca", "ca"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for osargs, msg := range map[string]string{
+		"--token t --secure --insecure":     "--secure conflicts with --insecure",
+		"--secure":                          "--user is required unless --token",
+		"--token t serve --cert c":          "serve: --cert requires --key",
+		"--token t --mode tls serve --ca c": "",
+		"--token t --mode tls serve":        "serve: --ca is required when --mode=tls",
+	} {
+		err := prepAndParse(&args, strings.Split(osargs, " "))
+		switch {
+		case msg == "" && err != nil:
+			t.Errorf("%s: %s", osargs, err)
+		case msg != "" && (err == nil || err.Error() != msg):
+			t.Errorf("%s: should be error '%s', %v", osargs, msg, err)
+		}
+	}
+
+	var invalid struct {
+		S string `requires:"--unknown"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as reference is unknown")
+	}
+
+	var invalidCmd struct {
+		Debug bool
+		Run   *struct {
+			S string `requires:"--nope"`
+			T string `required_if:"--debug=true"`
+		}
+	}
+	if _, err := prepareStructure(&invalidCmd); err == nil || err.Error() != "argument 's' references unknown argument --nope" {
+		t.Errorf("should be error, as reference of not selected command is unknown, %v", err)
+	}
+
+	s, err := prepareStructure(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(s.fields[1].constraints(), ","), "conflicts with --insecure", "failed describe dependency")
+}
+
+func TestRepeatableSlices(t *testing.T) {
+	var args struct {
+		Include []string `argum:"-I"`
+		Paths   []string `argum:"--paths" sep:":"`
+		Raw     []string `argum:"--raw" sep:""`
+		Ports   []int    `argum:"--ports,append" default:"80,443"`
+		Hosts   []string `argum:"--hosts" default:"localhost"`
+	}
+
+	err = prepAndParse(&args, []string{"-I", "a", "-I", "b,c", "--paths", "/bin:/usr/bin", "--raw", "a,b", "--raw=c,d", "--ports", "8080", "--hosts", "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check(t, strings.Join(args.Include, ";"), "a;b;c", "failed accumulate repeated slice argument")
+	check(t, strings.Join(args.Paths, ";"), "/bin;/usr/bin", "failed split by custom separator")
+	check(t, strings.Join(args.Raw, ";"), "a,b;c,d", "failed disable splitting")
+	check(t, len(args.Ports), 3, "failed append value to default")
+	check(t, strings.Join(args.Hosts, ";"), "example.com", "failed replace default")
+}
+
+func TestDuplicates(t *testing.T) {
+	var args struct {
+		Port  int    `argum:"--port"`
+		Host  string `argum:"--host" duplicate:"first"`
+		Debug bool   `argum:"-d"`
+		Name  string `argum:"--name" duplicate:"last"`
+		Cache bool   `argum:"-c,--cache,negatable"`
+		Color bool   `argum:"--color,negatable" duplicate:"first"`
+	}
+
+	err = prepAndParse(&args, []string{"--port", "1", "--port", "2"})
+	if err == nil || err.Error() != "--port given twice" {
+		t.Errorf("should be error, as argument given twice, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"--host", "a", "--name", "a", "--host", "b", "--name=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Host, "a", "failed keep first value")
+	check(t, args.Name, "b", "failed keep last value")
+
+	err = prepAndParse(&args, []string{"--cache", "--no-cache", "--color", "--no-color"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Cache, false, "failed override by negated form")
+	check(t, args.Color, true, "failed keep first value of negatable argument")
+
+	err = prepAndParse(&args, []string{"--no-cache", "-c", "--cache"})
+	if err == nil || err.Error() != "--cache given twice" {
+		t.Errorf("should be error with given key, %v", err)
+	}
+
+	Duplicates = DuplicateLast
+	defer func() { Duplicates = DuplicateError }()
+
+	err = prepAndParse(&args, []string{"--port", "1", "-d", "--port", "2", "-d", "false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Port, 2, "failed keep last value by global policy")
+	check(t, args.Debug, false, "failed keep last boolean value")
+}
+
+func TestAbbrevIgnoreCase(t *testing.T) {
+	var args struct {
+		Verbose bool   `argum:"--verbose"`
+		Version bool   `argum:"--version"`
+		Port    int    `argum:"-p,--port"`
+		Debug   bool   `argum:"--debug,negatable"`
+		Mode    string `argum:"--mode,fast|slow"`
+		Run     *struct {
+			Name string `argum:"pos"`
+		}
+	}
+
+	AllowAbbrev = true
+	IgnoreCase = true
+	defer func() { AllowAbbrev, IgnoreCase = false, false }()
+
+	err = prepAndParse(&args, []string{"--ver"})
+	if err == nil || err.Error() != "ambiguous option '--ver': --verbose, --version" {
+		t.Errorf("should be error, as abbreviation is ambiguous, %v", err)
+	}
+
+	err = prepAndParse(&args, []string{"--verb", "--Po=80", "--no-deb", "--MODE", "Fast", "RUN", "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Verbose, true, "failed parse abbreviation")
+	check(t, args.Port, 80, "failed parse abbreviation ignoring case")
+	check(t, args.Debug, false, "failed parse abbreviation of negated form")
+	check(t, args.Mode, "fast", "failed match variant ignoring case")
+	if args.Run == nil || args.Run.Name != "x" {
+		t.Error("failed match command ignoring case")
+	}
+
+	err = prepAndParse(&args, []string{"-P", "80"})
+	if err == nil {
+		t.Error("should be error, as short options are case-sensitive")
+	}
+
+	type Info struct {
+		Version bool `argum:"--version"`
+	}
+	var embargs struct {
+		Verbose bool `argum:"--verbose"`
+		Info    Info `argum:"emb"`
+	}
+
+	err = prepAndParse(&embargs, []string{"--ver"})
+	if err == nil || err.Error() != "ambiguous option '--ver': --verbose, --version" {
+		t.Errorf("should be error, as abbreviation is ambiguous with embedded option, %v", err)
+	}
+
+	err = prepAndParse(&embargs, []string{"--vers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, embargs.Info.Version, true, "failed parse abbreviation of embedded option")
+}
+
+func TestOperands(t *testing.T) {
+	var args struct {
+		Force bool     `argum:"-f"`
+		Files []string `argum:"pos"`
+	}
+
+	err = prepAndParse(&args, []string{"-f", "--", "-weird-file", "--force"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Force, true, "failed parse option before --")
+	check(t, strings.Join(args.Files, " "), "-weird-file --force", "failed parse operands after --")
+
+	type Common struct {
+		File string `argum:"pos"`
+	}
+	var embargs struct {
+		Force  bool   `argum:"-f"`
+		Common Common `argum:"emb"`
+	}
+
+	err = prepAndParse(&embargs, []string{"-f", "--", "-weird"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, embargs.Common.File, "-weird", "failed set operand to positional of embedded structure")
+
+	var cmdargs struct {
+		Rm *struct {
+			Recursive bool   `argum:"-r"`
+			Name      string `argum:"pos"`
+		}
+	}
+
+	err = prepAndParse(&cmdargs, []string{"rm", "-r", "--", "-x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdargs.Rm == nil || !cmdargs.Rm.Recursive || cmdargs.Rm.Name != "-x" {
+		t.Errorf("failed parse operand of command after --, %+v", cmdargs.Rm)
+	}
+
+	err = prepAndParse(&cmdargs, []string{"rm", "--", "a", "b"})
+	if err == nil {
+		t.Error("should be error, as there is extra operand")
+	}
+
+	POSIXMode = true
+	defer func() { POSIXMode = false }()
+
+	err = prepAndParse(&args, []string{"a", "-f", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Force, false, "failed stop option parsing at first operand")
+	check(t, strings.Join(args.Files, " "), "a -f b", "failed parse operands in POSIX mode")
+}
+
+func TestRest(t *testing.T) {
+	var args struct {
+		Run *struct {
+			Verbose bool     `argum:"-v"`
+			Env     []string `argum:"--env"`
+			Args    []string `argum:"rest"`
+		}
+	}
+
+	err = prepAndParse(&args, []string{"run", "--env", "X", "--", "docker", "ps", "-a", "'x,y'"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(args.Run.Env, " "), "X", "failed parse option before rest")
+	check(t, strings.Join(args.Run.Args, " "), "docker ps -a 'x,y'", "failed parse rest after --")
+
+	err = prepAndParse(&args, []string{"run", "--env=X", "ls", "--env", "Y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(args.Run.Env, " "), "X", "failed parse option before unexpected tail")
+	check(t, strings.Join(args.Run.Args, " "), "ls --env Y", "failed parse unexpected tail to rest")
+
+	err = prepAndParse(&args, []string{"run", "-v", "-la", "/tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, args.Run.Verbose, true, "failed parse option before unknown short options")
+	check(t, strings.Join(args.Run.Args, " "), "-la /tmp", "failed parse unknown short options to rest")
+
+	var abbrargs struct {
+		Verbose bool     `argum:"--verbose"`
+		Version bool     `argum:"--version"`
+		Args    []string `argum:"rest"`
+	}
+
+	AllowAbbrev = true
+	err = prepAndParse(&abbrargs, []string{"--verb", "--ver", "x"})
+	AllowAbbrev = false
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, abbrargs.Verbose, true, "failed parse abbreviation before rest")
+	check(t, strings.Join(abbrargs.Args, " "), "--ver x", "failed parse ambiguous abbreviation to rest")
+
+	var invalid struct {
+		Args string `argum:"rest"`
+	}
+	if _, err := prepareStructure(&invalid); err == nil {
+		t.Error("should be error, as rest argument is not []string")
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "nested.txt")
+	if err := os.WriteFile(nested, []byte("--name 'John Smith'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "args.txt")
+	if err := os.WriteFile(main, []byte("-o out.txt --count 3\n@"+nested+"\n\"a \\\"b\\\"\" 'c d'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := expandResponseFiles([]string{"@" + main, "--", "@" + main})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(args, "|"), `-o|out.txt|--count|3|--name|John Smith|a "b"|c d|--|@`+main, "failed expand response files")
+
+	paths := filepath.Join(dir, "paths.txt")
+	data := "C:\\Users\\me\\file.txt \"C:\\Users\\me\\file name.txt\"\r\n\n  '/tmp/dir with spaces/x.txt'\n--out \"C:\\Program Files\\app\"\n"
+	if err := os.WriteFile(paths, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err = expandResponseFiles([]string{"@" + paths})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, strings.Join(args, "|"), `C:\Users\me\file.txt|C:\Users\me\file name.txt|/tmp/dir with spaces/x.txt|--out|C:\Program Files\app`, "failed expand lines with paths")
+
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("-v\n'unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = expandResponseFiles([]string{"@" + invalid})
+	if err == nil || err.Error() != invalid+":2: unterminated quote '" {
+		t.Errorf("should be error with file name and line, %v", err)
+	}
+
+	loop := filepath.Join(dir, "loop.txt")
+	if err := os.WriteFile(loop, []byte("@"+loop), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = expandResponseFiles([]string{"@" + loop})
+	if err == nil || !strings.HasSuffix(err.Error(), loop+":1: response files nested too deeply") {
+		t.Errorf("should be error with file name and line, as response files are nested too deeply, %v", err)
+	}
+
+	include := filepath.Join(dir, "include.txt")
+	if err := os.WriteFile(include, []byte("-v\n@"+filepath.Join(dir, "missing.txt")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = expandResponseFiles([]string{"@" + include})
+	if err == nil || !strings.HasPrefix(err.Error(), include+":2: failed read response file") {
+		t.Errorf("should be error with file name and line of missing include, %v", err)
+	}
+}
+
 func check(t *testing.T, k, v interface{}, err string) {
 	if k != v {
 		t.Error(err)
@@ -663,7 +1912,5 @@ func prepAndParse(i interface{}, osargs []string) error {
 		return err
 	}
 
-	_, err = s.parseArgs(osargs)
-
-	return err
+	return s.parse(osargs)
 }